@@ -0,0 +1,21 @@
+data "uptycscspm_org_accounts" "workloads" {
+  profile_name            = "default"
+  organizational_unit_ids = ["ou-abcd-12345678"]
+  tags = {
+    environment = "production"
+  }
+  exclude_account_ids = ["123456789012"]
+}
+
+resource "uptycscspm_role" "workloads" {
+  for_each = toset(data.uptycscspm_org_accounts.workloads.ids)
+
+  profile_name     = "default"
+  account_id       = each.value
+  integration_name = "UptycsIntegration"
+  upt_account_id   = "123456789013"
+  external_id      = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  bucket_name      = "cloudtrail-logs"
+  bucket_region    = "us-east-1"
+  policy_document  = file("uptycs-readonly-policy.json")
+}
@@ -0,0 +1,12 @@
+data "uptycscspm_policy_document" "readonly" {
+  ciem                        = true
+  container_registry_scanning = true
+
+  statement = [
+    {
+      sid       = "UptycsExtraKms"
+      actions   = ["kms:Decrypt"]
+      resources = ["arn:aws:kms:us-east-1:123456789012:key/*"]
+    },
+  ]
+}
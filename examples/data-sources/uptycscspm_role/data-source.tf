@@ -0,0 +1,5 @@
+data "uptycscspm_role" "shared" {
+  profile_name     = "default"
+  account_id       = "123456789012"
+  integration_name = "UptycsIntegration"
+}
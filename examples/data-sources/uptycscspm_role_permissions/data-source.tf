@@ -0,0 +1,10 @@
+data "uptycscspm_role_permissions" "shared" {
+  profile_name     = "default"
+  account_id       = "123456789012"
+  integration_name = "UptycsIntegration"
+  policy_document  = data.uptycscspm_policy_document.read_only.json
+}
+
+output "blocked_actions" {
+  value = data.uptycscspm_role_permissions.shared.missing_permissions
+}
@@ -0,0 +1,14 @@
+data "uptycscspm_tenant_trust" "workload" {
+  account_id = "123456789012"
+}
+
+resource "uptycscspm_role" "workload" {
+  profile_name     = "management"
+  account_id       = data.uptycscspm_tenant_trust.workload.account_id
+  integration_name = "UptycsIntegration"
+  upt_account_id   = data.uptycscspm_tenant_trust.workload.upt_account_id
+  external_id      = data.uptycscspm_tenant_trust.workload.external_id
+  bucket_name      = "cloudtrail-logs"
+  bucket_region    = "us-east-1"
+  policy_document  = data.uptycscspm_policy_document.read_only.json
+}
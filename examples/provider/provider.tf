@@ -1,3 +1,11 @@
 provider "uptycscspm" {
-  # example configuration here
+  # List the organization from the security account, a delegated
+  # administrator, instead of the management account.
+  org_role_arn = "arn:aws:iam::123456789012:role/UptycsOrgReader"
+
+  # API key of the Uptycs tenant, as found in the key file downloaded from
+  # the console. The secret is best passed as UPTYCS_API_SECRET.
+  uptycs_host        = "mytenant.uptycs.io"
+  uptycs_api_key     = "E6B5B3C7D0A94E0E8A2B"
+  uptycs_customer_id = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
 }
@@ -0,0 +1,21 @@
+resource "uptycscspm_role" "workload" {
+  profile_name     = "management"
+  account_id       = "123456789012"
+  integration_name = "UptycsIntegration"
+  upt_account_id   = "123456789013"
+  bucket_name      = "cloudtrail-logs"
+  bucket_region    = "us-east-1"
+  policy_document  = data.uptycscspm_policy_document.read_only.json
+}
+
+resource "uptycscspm_aws_integration" "workload" {
+  account_id  = uptycscspm_role.workload.account_id
+  name        = "workload"
+  role_arn    = uptycscspm_role.workload.role
+  external_id = uptycscspm_role.workload.external_id
+
+  log_sources = [{
+    bucket_name   = uptycscspm_role.workload.bucket_name
+    bucket_region = uptycscspm_role.workload.bucket_region
+  }]
+}
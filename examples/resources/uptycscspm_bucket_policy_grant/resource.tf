@@ -0,0 +1,21 @@
+resource "uptycscspm_role" "audit" {
+  profile_name     = "management"
+  account_id       = "123456789012"
+  integration_name = "UptycsIntegration"
+  upt_account_id   = "012345678912"
+  external_id      = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  bucket_name      = "example-log-archive"
+  bucket_region    = "us-east-1"
+  policy_document  = data.uptycscspm_policy_document.readonly.json
+}
+
+resource "uptycscspm_bucket_policy_grant" "audit" {
+  profile_name  = "management"
+  account_id    = "234567890123"
+  bucket_name   = "example-log-archive"
+  bucket_region = "us-east-1"
+  role_arn      = uptycscspm_role.audit.role
+  prefix        = "AWSLogs/"
+}
+
+data "uptycscspm_policy_document" "readonly" {}
@@ -0,0 +1,6 @@
+resource "uptycscspm_event_forwarding" "workload" {
+  profile_name  = "management"
+  account_id    = "123456789012"
+  regions       = ["us-east-1", "us-west-2", "eu-west-1"]
+  event_bus_arn = "arn:aws:events:us-east-1:012345678912:event-bus/uptycs"
+}
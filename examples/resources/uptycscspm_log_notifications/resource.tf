@@ -0,0 +1,13 @@
+resource "uptycscspm_log_notifications" "cloudtrail" {
+  profile_name  = "management"
+  account_id    = "234567890123"
+  bucket_name   = "example-log-archive"
+  bucket_region = "us-east-1"
+  name          = "uptycs-cloudtrail"
+  prefix        = "AWSLogs/"
+  role_arn      = "arn:aws:iam::123456789012:role/UptycsIntegration"
+}
+
+data "uptycscspm_policy_document" "readonly" {
+  log_queue_arns = [uptycscspm_log_notifications.cloudtrail.queue_arn]
+}
@@ -0,0 +1,23 @@
+resource "uptycscspm_org_integration" "workloads" {
+  profile_name            = "management"
+  integration_name        = "UptycsIntegration"
+  upt_account_id          = "012345678912"
+  external_id             = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  bucket_name             = "uptycs-cloudtrail-logs"
+  bucket_region           = "us-east-1"
+  policy_document         = data.uptycscspm_policy_document.readonly.json
+  organizational_unit_ids = ["ou-abcd-12345678"]
+  auto_enroll             = true
+  auto_unenroll           = true
+  concurrency             = 20
+  failure_mode            = "threshold"
+  failure_threshold       = "5%"
+
+  timeouts {
+    create = "60m"
+    update = "60m"
+    delete = "60m"
+  }
+}
+
+data "uptycscspm_policy_document" "readonly" {}
@@ -0,0 +1,34 @@
+data "uptycscspm_org_accounts" "all" {
+  profile_name = "management"
+}
+
+resource "uptycscspm_org_trail" "uptycs" {
+  profile_name     = "management"
+  trail_name       = "uptycs-org-trail"
+  bucket_name      = "example-org-cloudtrail-logs"
+  bucket_region    = "us-east-1"
+  integration_name = "UptycsIntegration"
+  account_ids      = data.uptycscspm_org_accounts.all.ids
+  kms_encryption   = true
+}
+
+resource "uptycscspm_org_integration" "workloads" {
+  profile_name     = "management"
+  integration_name = "UptycsIntegration"
+  upt_account_id   = "012345678912"
+  external_id      = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  bucket_name      = uptycscspm_org_trail.uptycs.bucket_name
+  bucket_region    = uptycscspm_org_trail.uptycs.bucket_region
+  bucket_prefix    = uptycscspm_org_trail.uptycs.prefix
+  policy_document  = data.uptycscspm_policy_document.readonly.json
+}
+
+data "uptycscspm_policy_document" "readonly" {
+  statement = [
+    {
+      sid       = "DecryptCloudTrailLogs"
+      actions   = ["kms:Decrypt"]
+      resources = [uptycscspm_org_trail.uptycs.kms_key_arn]
+    },
+  ]
+}
@@ -0,0 +1,20 @@
+resource "uptycscspm_role" "workload" {
+  profile_name          = "management"
+  account_id            = "123456789012"
+  integration_name      = "UptycsIntegration"
+  upt_account_id        = "123456789013"
+  bucket_name           = "cloudtrail-logs"
+  bucket_region         = "us-east-1"
+  policy_document       = data.uptycscspm_policy_document.read_only.json
+  rotation_grace_period = "72h"
+
+  trust {
+    principal_arns = ["arn:aws:iam::123456789013:role/UptycsIntegration"]
+
+    condition {
+      test     = "IpAddress"
+      variable = "aws:SourceIp"
+      values   = ["203.0.113.0/24"]
+    }
+  }
+}
@@ -3,6 +3,8 @@ package aws
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,26 +22,6 @@ const ReadOnlyPolicyName = "UptycsReadOnlyPolicy"
 const ViewOnlyAccessArn = "arn:aws:iam::aws:policy/job-function/ViewOnlyAccess"
 const SecurityAuditArn = "arn:aws:iam::aws:policy/SecurityAudit"
 
-func getUptycsPolicyDoc(uptAccountId string, externalID string) string {
-	return `{
-    "Version": "2012-10-17",
-    "Statement": [
-        {
-            "Effect": "Allow",
-            "Principal": {
-                "AWS": "arn:aws:iam::` + uptAccountId + `:root"
-            },
-            "Action": "sts:AssumeRole",
-            "Condition": {
-                "StringEquals": {
-                    "sts:ExternalId": "` + externalID + `"
-                }
-            }
-        }
-    ]
-}`
-}
-
 func getAwsConfig(ctx context.Context, profileName string, regionCode string, roleArn string) (*aws.Config, error) {
 	cfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(regionCode),
@@ -52,7 +34,7 @@ func getAwsConfig(ctx context.Context, profileName string, regionCode string, ro
 	// referenced by the role ARN.
 	stsSvc := sts.NewFromConfig(cfg)
 	creds := stscreds.NewAssumeRoleProvider(stsSvc, roleArn, func(options *stscreds.AssumeRoleOptions) {
-		options.Duration = time.Duration(60) * time.Minute
+		options.Duration = sessionDuration(ctx)
 		//options.ExternalID = &externalID
 	})
 	cfg.Credentials = aws.NewCredentialsCache(creds)
@@ -60,9 +42,32 @@ func getAwsConfig(ctx context.Context, profileName string, regionCode string, ro
 	return &cfg, nil
 }
 
-func createIntegrationRole(ctx context.Context, svc *iam.Client, integrationName *string, uptAccountId string, externalID string) (string, error) {
+// sessionDuration sizes the assumed-role session to the deadline of ctx, so
+// a session never outlives the operation that requested it. STS accepts
+// durations between 15 minutes and the role's maximum, which defaults to one
+// hour.
+func sessionDuration(ctx context.Context) time.Duration {
+	const (
+		minSessionDuration = 15 * time.Minute
+		maxSessionDuration = 60 * time.Minute
+	)
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return maxSessionDuration
+	}
+	d := time.Until(deadline).Round(time.Minute) + time.Minute
+	if d < minSessionDuration {
+		return minSessionDuration
+	}
+	if d > maxSessionDuration {
+		return maxSessionDuration
+	}
+	return d
+}
+
+func createIntegrationRole(ctx context.Context, svc *iam.Client, integrationName *string, trust Trust) (string, error) {
 	desc := "Uptycs integration role"
-	assumeRolePolicyDoc := getUptycsPolicyDoc(uptAccountId, externalID)
+	assumeRolePolicyDoc := trust.policy().String()
 	input := iam.CreateRoleInput{
 		AssumeRolePolicyDocument: &assumeRolePolicyDoc,
 		RoleName:                 integrationName,
@@ -78,6 +83,20 @@ func createIntegrationRole(ctx context.Context, svc *iam.Client, integrationName
 	return *roleOut.Role.Arn, nil
 }
 
+// UpdateIntegrationTrust replaces the trust policy of the integration role
+// in place, so that Uptycs can keep assuming it with any of the external IDs
+// while the tenant is updated.
+func UpdateIntegrationTrust(ctx context.Context, svc *iam.Client, integrationName string, trust Trust) error {
+	document := trust.policy().String()
+	if _, err := svc.UpdateAssumeRolePolicy(ctx, &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       &integrationName,
+		PolicyDocument: &document,
+	}); err != nil {
+		return newError(StepUpdateTrust, err)
+	}
+	return nil
+}
+
 func createReadOnlyInlinePolicy(ctx context.Context, svc *iam.Client, roleName string, policyDocument string) (string, error) {
 	name := ReadOnlyPolicyName
 	doc := policyDocument
@@ -93,19 +112,42 @@ func createReadOnlyInlinePolicy(ctx context.Context, svc *iam.Client, roleName s
 	return name, nil
 }
 
-func createBucketPolicy(ctx context.Context, svc *iam.Client, roleName string, bucketName string) (string, error) {
-	name := roleName + "-CloudtrailBucketPolicy"
+// bucketPolicyDocument returns the policy letting a role read the logs in
+// bucketName, under bucketPrefix when it is set.
+func bucketPolicyDocument(bucketName string, bucketPrefix string) string {
 	policyDocument := `{
 		"Version": "2012-10-17",
 		"Statement": [
 			{
 				"Effect": "Allow",
 				"Action": "s3:GetObject",
-				"Resource": "arn:aws:s3:::%s/*"
+				"Resource": "arn:aws:s3:::%s/%s*"
 			}
 		]
 	}`
-	doc := fmt.Sprintf(policyDocument, bucketName)
+	return fmt.Sprintf(policyDocument, bucketName, strings.TrimPrefix(bucketPrefix, "/"))
+}
+
+// bucketPolicyLocation returns the bucket and the key prefix a policy of
+// bucketPolicyDocument lets the role read.
+func bucketPolicyLocation(policy *PolicyDocument) (string, string) {
+	bucketName, bucketPrefix := "", ""
+	for _, statement := range policy.Statement {
+		for _, resource := range statement.Resource {
+			if strings.HasPrefix(resource, "arn:aws:s3:::") {
+				objects := strings.TrimSuffix(strings.TrimPrefix(resource, "arn:aws:s3:::"), "*")
+				bucketName, bucketPrefix, _ = strings.Cut(objects, "/")
+			}
+		}
+	}
+	return bucketName, bucketPrefix
+}
+
+// createBucketPolicy creates the managed policy letting roleName read the
+// logs in bucketName, under bucketPrefix when it is set.
+func createBucketPolicy(ctx context.Context, svc *iam.Client, roleName string, bucketName string, bucketPrefix string) (string, error) {
+	name := roleName + "-CloudtrailBucketPolicy"
+	doc := bucketPolicyDocument(bucketName, bucketPrefix)
 	input := iam.CreatePolicyInput{
 		PolicyName:     &name,
 		PolicyDocument: &doc,
@@ -176,34 +218,84 @@ func detachPolicyToRole(ctx context.Context, svc *iam.Client, policyArn string,
 	return nil
 }
 
-func GetAwsIamClient(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string) (*iam.Client, error) {
-	roleArn := fmt.Sprintf("arn:aws:iam::%s:role/OrganizationAccountAccessRole", childAccountID)
+func getOrgAccessRoleArn(childAccountID string, roleToAssume string) string {
 	if roleToAssume != "" {
-		roleArn = fmt.Sprintf("arn:aws:iam::%s:role/%s", childAccountID, roleToAssume)
+		return fmt.Sprintf("arn:aws:iam::%s:role/%s", childAccountID, roleToAssume)
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:role/OrganizationAccountAccessRole", childAccountID)
+}
+
+// getAwsConfigForAccount returns a configuration for childAccountID. The
+// organization access role roleToAssume is assumed in that account, unless
+// useCallerCredentials is set, in which case the profile's own credentials
+// are used and must belong to childAccountID.
+func getAwsConfigForAccount(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string, useCallerCredentials bool) (*aws.Config, error) {
+	if !useCallerCredentials {
+		sess, err := getAwsConfig(ctx, profileName, regionCode, getOrgAccessRoleArn(childAccountID, roleToAssume))
+		if err != nil {
+			return nil, newError(StepLoadConfig, err)
+		}
+		return sess, nil
 	}
-	sess, err := getAwsConfig(ctx, profileName, regionCode, roleArn)
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(regionCode),
+		config.WithSharedConfigProfile(profileName),
+	)
+	if err != nil {
+		return nil, newError(StepLoadConfig, err)
+	}
+	callerAccountID, err := getCallerAccountID(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if callerAccountID != childAccountID {
+		return nil, &Error{
+			Kind: ErrAccountMismatch,
+			Step: StepGetCallerIdentity,
+			Err:  fmt.Errorf("profile %s belongs to account %s, not %s", profileName, callerAccountID, childAccountID),
+		}
+	}
+	return &cfg, nil
+}
+
+func getCallerAccountID(ctx context.Context, cfg aws.Config) (string, error) {
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", newError(StepGetCallerIdentity, err)
+	}
+	if identity.Account == nil {
+		return "", newError(StepGetCallerIdentity, fmt.Errorf("invalid GetCallerIdentityOutput"))
+	}
+	return *identity.Account, nil
+}
+
+func GetAwsIamClient(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string, useCallerCredentials bool) (*iam.Client, error) {
+	sess, err := getAwsConfigForAccount(ctx, profileName, regionCode, childAccountID, roleToAssume, useCallerCredentials)
 	if err != nil {
 		return nil, err
 	}
 	svc := iam.NewFromConfig(*sess)
 	if svc == nil {
-		return nil, fmt.Errorf("failed to create client with profile=%s, region=%s, role=%s", profileName, regionCode, roleArn)
+		return nil, newError(StepCreateClient, fmt.Errorf("failed to create client with profile=%s, region=%s, account=%s", profileName, regionCode, childAccountID))
 	}
 	return svc, nil
 }
 
-func getAwsS3Client(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string) (*storage.Client, error) {
-	roleArn := fmt.Sprintf("arn:aws:iam::%s:role/OrganizationAccountAccessRole", childAccountID)
-	if roleToAssume != "" {
-		roleArn = fmt.Sprintf("arn:aws:iam::%s:role/%s", childAccountID, roleToAssume)
-	}
-	sess, err := getAwsConfig(ctx, profileName, regionCode, roleArn)
+func GetAwsS3Client(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string, useCallerCredentials bool) (*storage.Client, error) {
+	sess, err := getAwsConfigForAccount(ctx, profileName, regionCode, childAccountID, roleToAssume, useCallerCredentials)
 	if err != nil {
 		return nil, err
 	}
+	return newS3Client(sess, profileName, regionCode, childAccountID)
+}
+
+// newS3Client is GetAwsS3Client for a configuration already loaded, shared
+// with the clients of other services.
+func newS3Client(sess *aws.Config, profileName string, regionCode string, childAccountID string) (*storage.Client, error) {
 	svc := storage.NewFromConfig(*sess)
 	if svc == nil {
-		return nil, fmt.Errorf("failed to create client with profile=%s, region=%s, role=%s", profileName, regionCode, roleArn)
+		return nil, newError(StepCreateClient, fmt.Errorf("failed to create client with profile=%s, region=%s, account=%s", profileName, regionCode, childAccountID))
 	}
 	return svc, nil
 }
@@ -214,154 +306,317 @@ func GetIntegrationRoleName(ctx context.Context, svc *iam.Client, integrationNam
 	}
 	roleOut, errGet := svc.GetRole(ctx, &input)
 	if errGet != nil {
-		return "", errGet
+		return "", newError(StepGetRole, errGet)
 	}
 	if roleOut == nil || roleOut.Role == nil || roleOut.Role.Arn == nil {
-		return "", fmt.Errorf("invalid roleOutput for %s", integrationName)
+		return "", newError(StepGetRole, fmt.Errorf("invalid roleOutput for %s", integrationName))
 	}
 	return *roleOut.Role.Arn, nil
 }
 
+// RoleStatus describes the parts of an integration role found in an
+// account, so that callers can detect a role that was partially deleted.
+type RoleStatus struct {
+	Arn                string
+	InlinePolicy       bool
+	AttachedPolicyArns map[string]bool
+}
+
+// MissingPolicies returns the policies the integration expects on the role
+// but which are not present. bucketPolicyArn is checked only when not empty.
+func (s *RoleStatus) MissingPolicies(bucketPolicyArn string) []string {
+	var missing []string
+	if !s.InlinePolicy {
+		missing = append(missing, ReadOnlyPolicyName)
+	}
+	for _, arn := range []string{ViewOnlyAccessArn, SecurityAuditArn, bucketPolicyArn} {
+		if arn != "" && !s.AttachedPolicyArns[arn] {
+			missing = append(missing, arn)
+		}
+	}
+	return missing
+}
+
+// GetCloudtrailBucketPolicyArn returns the ARN of the customer managed policy
+// granting the integration role read access to the CloudTrail bucket.
+func GetCloudtrailBucketPolicyArn(accountId string, integrationName string) string {
+	return "arn:aws:iam::" + accountId + ":policy/" + integrationName + "-CloudtrailBucketPolicy"
+}
+
+// listRolePolicies returns the ARNs of the managed policies attached to the
+// role and the names of its inline policies. Both maps are always usable,
+// even when an error is returned.
+func listRolePolicies(ctx context.Context, svc *iam.Client, integrationName string) (map[string]bool, map[string]bool, error) {
+	attachedPoliciesMap := make(map[string]bool, 0)
+	inlinePoliciesMap := make(map[string]bool, 0)
+
+	params := &iam.ListAttachedRolePoliciesInput{
+		RoleName: &integrationName,
+	}
+	policiesOuput, ListPolicyErr := svc.ListAttachedRolePolicies(ctx, params)
+	if ListPolicyErr != nil {
+		return attachedPoliciesMap, inlinePoliciesMap, newError(StepListPolicies, ListPolicyErr)
+	}
+	for _, attachedPolicy := range policiesOuput.AttachedPolicies {
+		attachedPoliciesMap[*attachedPolicy.PolicyArn] = true
+	}
+
+	inlinePolicyParams := &iam.ListRolePoliciesInput{
+		RoleName: &integrationName,
+	}
+	inlinePoliciesOp, listInlinePoliciesErr := svc.ListRolePolicies(ctx, inlinePolicyParams)
+	if listInlinePoliciesErr != nil {
+		return attachedPoliciesMap, inlinePoliciesMap, newError(StepListPolicies, listInlinePoliciesErr)
+	}
+	for _, inlinePolicy := range inlinePoliciesOp.PolicyNames {
+		inlinePoliciesMap[inlinePolicy] = true
+	}
+	return attachedPoliciesMap, inlinePoliciesMap, nil
+}
+
+// GetIntegrationRoleStatus looks up the integration role and the policies
+// attached to it. A role that does not exist is reported as an error of kind
+// ErrNoSuchEntity.
+func GetIntegrationRoleStatus(ctx context.Context, svc *iam.Client, integrationName string) (*RoleStatus, error) {
+	roleArn, err := GetIntegrationRoleName(ctx, svc, integrationName)
+	if err != nil {
+		return nil, err
+	}
+	attachedPoliciesMap, inlinePoliciesMap, err := listRolePolicies(ctx, svc, integrationName)
+	if err != nil {
+		return nil, err
+	}
+	return &RoleStatus{
+		Arn:                roleArn,
+		InlinePolicy:       inlinePoliciesMap[ReadOnlyPolicyName],
+		AttachedPolicyArns: attachedPoliciesMap,
+	}, nil
+}
+
+// RoleDescription is the configuration of an existing integration role.
+type RoleDescription struct {
+	RoleStatus
+	TrustPrincipals      []string
+	ExternalIDs          []string
+	InlinePolicyDocument string
+	BucketName           string
+	BucketPrefix         string
+}
+
+// DescribeIntegrationRole reads the configuration of an existing integration
+// role: its trust policy, inline policy, attached managed policies and the
+// CloudTrail bucket it was granted access to.
+func DescribeIntegrationRole(ctx context.Context, svc *iam.Client, accountId string, integrationName string) (*RoleDescription, error) {
+	roleOut, err := svc.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: &integrationName,
+	})
+	if err != nil {
+		return nil, newError(StepGetRole, err)
+	}
+	if roleOut == nil || roleOut.Role == nil || roleOut.Role.Arn == nil {
+		return nil, newError(StepGetRole, fmt.Errorf("invalid roleOutput for %s", integrationName))
+	}
+	attachedPoliciesMap, inlinePoliciesMap, err := listRolePolicies(ctx, svc, integrationName)
+	if err != nil {
+		return nil, err
+	}
+	description := &RoleDescription{
+		RoleStatus: RoleStatus{
+			Arn:                *roleOut.Role.Arn,
+			InlinePolicy:       inlinePoliciesMap[ReadOnlyPolicyName],
+			AttachedPolicyArns: attachedPoliciesMap,
+		},
+	}
+
+	if roleOut.Role.AssumeRolePolicyDocument != nil {
+		trustPolicy, err := ParsePolicyDocument(*roleOut.Role.AssumeRolePolicyDocument)
+		if err != nil {
+			return nil, newError(StepGetRole, fmt.Errorf("invalid trust policy for %s: %w", integrationName, err))
+		}
+		description.TrustPrincipals = trustPolicy.principals()
+		description.ExternalIDs = trustPolicy.conditionValues("sts:ExternalId")
+	}
+
+	if description.InlinePolicy {
+		name := ReadOnlyPolicyName
+		policyOut, err := svc.GetRolePolicy(ctx, &iam.GetRolePolicyInput{
+			RoleName:   &integrationName,
+			PolicyName: &name,
+		})
+		if err != nil {
+			return nil, newError(StepGetInlinePolicy, err)
+		}
+		if policyOut.PolicyDocument != nil {
+			description.InlinePolicyDocument, err = url.QueryUnescape(*policyOut.PolicyDocument)
+			if err != nil {
+				return nil, newError(StepGetInlinePolicy, err)
+			}
+		}
+	}
+
+	bucketPolicyArn := GetCloudtrailBucketPolicyArn(accountId, integrationName)
+	if attachedPoliciesMap[bucketPolicyArn] {
+		bucketPolicy, err := getManagedPolicyDocument(ctx, svc, bucketPolicyArn)
+		if err != nil {
+			return nil, err
+		}
+		description.BucketName, description.BucketPrefix = bucketPolicyLocation(bucketPolicy)
+	}
+	return description, nil
+}
+
+func getManagedPolicyDocument(ctx context.Context, svc *iam.Client, policyArn string) (*PolicyDocument, error) {
+	policyOut, err := svc.GetPolicy(ctx, &iam.GetPolicyInput{
+		PolicyArn: &policyArn,
+	})
+	if err != nil {
+		return nil, newError(StepGetManagedPolicy, err)
+	}
+	if policyOut.Policy == nil || policyOut.Policy.DefaultVersionId == nil {
+		return nil, newError(StepGetManagedPolicy, fmt.Errorf("invalid GetPolicyOutput for %s", policyArn))
+	}
+	versionOut, err := svc.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: &policyArn,
+		VersionId: policyOut.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return nil, newError(StepGetManagedPolicy, err)
+	}
+	if versionOut.PolicyVersion == nil || versionOut.PolicyVersion.Document == nil {
+		return nil, newError(StepGetManagedPolicy, fmt.Errorf("invalid GetPolicyVersionOutput for %s", policyArn))
+	}
+	document, err := ParsePolicyDocument(*versionOut.PolicyVersion.Document)
+	if err != nil {
+		return nil, newError(StepGetManagedPolicy, err)
+	}
+	return document, nil
+}
+
+// CreateUptycsCspmResources creates the integration role, or completes an
+// existing one, and returns its ARN. When a step fails, what the call created
+// is removed again, also when it re-creates the role of an update, whose old
+// role is already gone: the next refresh then plans to create it.
 func CreateUptycsCspmResources(
 	ctx context.Context,
 	svc *iam.Client, integrationName string,
-	uptAccountID string,
-	externalID string,
+	trust Trust,
 	bucketName string,
 	bucketRegion string,
+	bucketPrefix string,
 	profileName string,
 	accountId string,
 	policyDocument string,
 	roleToAssume string,
-	isUpdate bool,
+	useCallerCredentials bool,
 ) (string, error) {
+	var created journal
+	// fail wraps err with the step that failed and removes what this call
+	// created before the failure.
+	fail := func(step string, err error) (string, error) {
+		awsErr := wrapError(step, err)
+		awsErr.CleanupErr = created.rollback(ctx)
+		return "", awsErr
+	}
+
 	roleArn := ""
 	existRoleArn, err := GetIntegrationRoleName(ctx, svc, integrationName)
 	if err != nil {
-		newRoleArn, roleErr := createIntegrationRole(ctx, svc, &integrationName, uptAccountID, externalID)
+		newRoleArn, roleErr := createIntegrationRole(ctx, svc, &integrationName, trust)
 		if roleErr != nil {
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", roleErr
+			return fail(StepCreateRole, roleErr)
 		}
+		created.record(StepCreateRole, func(ctx context.Context) error {
+			return deleteIntegrationRole(ctx, svc, integrationName)
+		})
 		roleArn = newRoleArn
 	} else {
 		roleArn = existRoleArn
 	}
 
-	params := &iam.ListAttachedRolePoliciesInput{
-		RoleName: &integrationName,
-	}
-	attachedPoliciesMap := make(map[string]bool, 0)
-	inlinePoliciesMap := make(map[string]bool, 0)
-	policiesOuput, ListPolicyErr := svc.ListAttachedRolePolicies(ctx, params)
-	if ListPolicyErr == nil {
-		for _, attachedPolicy := range policiesOuput.AttachedPolicies {
-			attachedPoliciesMap[*attachedPolicy.PolicyArn] = true
-		}
-	}
-
-	inlinePolicyParams := &iam.ListRolePoliciesInput{
-		RoleName: &integrationName,
-	}
-	inlinePoliciesOp, listInlinePoliciesErr := svc.ListRolePolicies(ctx, inlinePolicyParams)
-
-	if listInlinePoliciesErr == nil {
-		for _, inlinePolicy := range inlinePoliciesOp.PolicyNames {
-			inlinePoliciesMap[inlinePolicy] = true
-		}
-	}
+	// a listing error leaves the maps empty, the missing policies are then
+	// (re)applied below
+	attachedPoliciesMap, inlinePoliciesMap, _ := listRolePolicies(ctx, svc, integrationName)
 
 	if _, found := inlinePoliciesMap[ReadOnlyPolicyName]; !found {
 		_, inlinePolErr := createReadOnlyInlinePolicy(ctx, svc, integrationName, policyDocument)
 		if inlinePolErr != nil {
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", inlinePolErr
+			return fail(StepPutInlinePolicy, inlinePolErr)
 		}
+		created.record(StepPutInlinePolicy, func(ctx context.Context) error {
+			return deleteReadOnlyInlinePolicy(ctx, svc, integrationName)
+		})
 	}
-	if _, found := attachedPoliciesMap[ViewOnlyAccessArn]; !found {
-		if attachErr := attachPolicyToRole(ctx, svc, ViewOnlyAccessArn, integrationName); attachErr != nil {
-			// clean-up already created resources
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", attachErr
+	for _, managedPolicyArn := range []string{ViewOnlyAccessArn, SecurityAuditArn} {
+		if _, found := attachedPoliciesMap[managedPolicyArn]; found {
+			continue
 		}
-	}
-
-	if _, found := attachedPoliciesMap[SecurityAuditArn]; !found {
-		if attachErr := attachPolicyToRole(ctx, svc, SecurityAuditArn, integrationName); attachErr != nil {
-			// clean-up already created resources
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", attachErr
+		if attachErr := attachPolicyToRole(ctx, svc, managedPolicyArn, integrationName); attachErr != nil {
+			return fail(StepAttachPolicy, attachErr)
 		}
-
+		policyArn := managedPolicyArn
+		created.record(StepAttachPolicy, func(ctx context.Context) error {
+			return detachPolicyToRole(ctx, svc, policyArn, integrationName)
+		})
 	}
 
 	if bucketName != "" {
-		//get s3 client
-		s3Client, s3ClientErr := getAwsS3Client(ctx, profileName, bucketRegion, accountId, roleToAssume)
-		if s3ClientErr != nil {
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", s3ClientErr
+		if bucketErr := CheckIntegrationBucket(ctx, profileName, bucketName, bucketRegion, accountId, roleToAssume, useCallerCredentials); bucketErr != nil {
+			return fail(StepValidateBucket, bucketErr)
 		}
 
-		//validate s3 bucket
-		input := &storage.HeadBucketInput{
-			Bucket: &bucketName,
-		}
-		_, s3ValidationErr := s3Client.HeadBucket(ctx, input)
-		if s3ValidationErr != nil {
-			if !isUpdate {
-				DeleteUptycsCspmResources(ctx, svc, integrationName)
-			}
-			return "", s3ValidationErr
-		}
-
-		cloudtrailBucketPolicyArn := "arn:aws:iam::" + accountId + ":policy/" + integrationName + "-CloudtrailBucketPolicy"
+		cloudtrailBucketPolicyArn := GetCloudtrailBucketPolicyArn(accountId, integrationName)
 
 		if _, found := attachedPoliciesMap[cloudtrailBucketPolicyArn]; !found {
 			policyParams := &iam.GetPolicyInput{
 				PolicyArn: &cloudtrailBucketPolicyArn,
 			}
 			if _, policyErr := svc.GetPolicy(ctx, policyParams); policyErr != nil {
-				_, policyErr1 := createBucketPolicy(ctx, svc, integrationName, bucketName)
+				_, policyErr1 := createBucketPolicy(ctx, svc, integrationName, bucketName, bucketPrefix)
 				if policyErr1 != nil {
-					if !isUpdate {
-						DeleteUptycsCspmResources(ctx, svc, integrationName)
-					}
-					return "", policyErr
+					return fail(StepCreateBucketPolicy, policyErr1)
 				}
+				created.record(StepCreateBucketPolicy, func(ctx context.Context) error {
+					return deleteBucketPolicy(ctx, svc, cloudtrailBucketPolicyArn)
+				})
 			}
 
 			if attachErr := attachPolicyToRole(ctx, svc, cloudtrailBucketPolicyArn, integrationName); attachErr != nil {
-				// clean-up already created resources
-				if !isUpdate {
-					DeleteUptycsCspmResources(ctx, svc, integrationName)
-				}
-				return "", attachErr
+				return fail(StepAttachPolicy, attachErr)
 			}
-
+			created.record(StepAttachPolicy, func(ctx context.Context) error {
+				return detachPolicyToRole(ctx, svc, cloudtrailBucketPolicyArn, integrationName)
+			})
 		}
 
 	}
 	return roleArn, nil
 }
 
+// CheckIntegrationBucket checks that the CloudTrail bucket of an integration
+// exists in bucketRegion. It lets an update fail before it deletes the role
+// it re-creates.
+func CheckIntegrationBucket(ctx context.Context, profileName string, bucketName string, bucketRegion string, accountId string, roleToAssume string, useCallerCredentials bool) error {
+	s3Client, err := GetAwsS3Client(ctx, profileName, bucketRegion, accountId, roleToAssume, useCallerCredentials)
+	if err != nil {
+		return newError(StepCreateClient, err)
+	}
+	if _, err := s3Client.HeadBucket(ctx, &storage.HeadBucketInput{Bucket: &bucketName}); err != nil {
+		return newError(StepValidateBucket, err)
+	}
+	// HeadBucket does not catch a wrong bucket_region, the location does.
+	if err := checkBucketRegion(ctx, s3Client, bucketName, bucketRegion); err != nil && !isAccessDenied(err) {
+		return newError(StepValidateBucket, err)
+	}
+	return nil
+}
+
 func DeleteUptycsCspmResources(ctx context.Context, svc *iam.Client, integrationName string) error {
 	params := &iam.ListAttachedRolePoliciesInput{
 		RoleName: &integrationName,
 	}
 	policiesOuput, ListPolicyErr := svc.ListAttachedRolePolicies(ctx, params)
 	if ListPolicyErr != nil {
-		return ListPolicyErr
+		return newError(StepListPolicies, ListPolicyErr)
 	}
 	cloudtrailBucketPolicyName := integrationName + "-CloudtrailBucketPolicy"
 
@@ -369,18 +624,18 @@ func DeleteUptycsCspmResources(ctx context.Context, svc *iam.Client, integration
 		switch *policy.PolicyName {
 		case cloudtrailBucketPolicyName:
 			if detachErr := detachPolicyToRole(ctx, svc, *policy.PolicyArn, integrationName); detachErr != nil {
-				return detachErr
+				return newError(StepDetachPolicy, detachErr)
 			}
 			if delPolicyErr := deleteBucketPolicy(ctx, svc, *policy.PolicyArn); delPolicyErr != nil {
-				return delPolicyErr
+				return newError(StepDeleteBucketPolicy, delPolicyErr)
 			}
 		case "SecurityAudit":
 			if detachErr := detachPolicyToRole(ctx, svc, SecurityAuditArn, integrationName); detachErr != nil {
-				return detachErr
+				return newError(StepDetachPolicy, detachErr)
 			}
 		case "ViewOnlyAccess":
 			if detachErr := detachPolicyToRole(ctx, svc, ViewOnlyAccessArn, integrationName); detachErr != nil {
-				return detachErr
+				return newError(StepDetachPolicy, detachErr)
 			}
 		}
 	}
@@ -389,18 +644,18 @@ func DeleteUptycsCspmResources(ctx context.Context, svc *iam.Client, integration
 	}
 	inlinePoliciesOp, listInlinePoliciesErr := svc.ListRolePolicies(ctx, inlinePolicyParams)
 	if listInlinePoliciesErr != nil {
-		return listInlinePoliciesErr
+		return newError(StepListPolicies, listInlinePoliciesErr)
 	}
 	for _, inlinePolicy := range inlinePoliciesOp.PolicyNames {
 		if inlinePolicy == ReadOnlyPolicyName {
 			if readOnlyPolErr := deleteReadOnlyInlinePolicy(ctx, svc, integrationName); readOnlyPolErr != nil {
-				return readOnlyPolErr
+				return newError(StepDeleteInlinePolicy, readOnlyPolErr)
 			}
 		}
 
 	}
 	if roleErr := deleteIntegrationRole(ctx, svc, integrationName); roleErr != nil {
-		return roleErr
+		return newError(StepDeleteRole, roleErr)
 	}
 	return nil
 
@@ -419,29 +674,24 @@ func getAwsConfigForOrg(ctx context.Context, profileName string) (*aws.Config, e
 	return &cfg, nil
 }
 
-func GetOrgClient(ctx context.Context, profileName string) (*org.Client, error) {
-	sess, err := getAwsConfigForOrg(ctx, profileName)
+// GetOrgClient returns an Organizations client for the organization of
+// profileName. When roleArn is set, the profile's credentials are first used
+// to assume that role, for example a role of a delegated administrator
+// account.
+func GetOrgClient(ctx context.Context, profileName string, roleArn string) (*org.Client, error) {
+	var sess *aws.Config
+	var err error
+	if roleArn != "" {
+		sess, err = getAwsConfig(ctx, profileName, "us-east-1", roleArn)
+	} else {
+		sess, err = getAwsConfigForOrg(ctx, profileName)
+	}
 	if err != nil {
-		return nil, err
+		return nil, newError(StepLoadConfig, err)
 	}
 	svc := org.NewFromConfig(*sess)
 	if svc == nil {
-		return nil, fmt.Errorf("failed to create org client with profile=%s, region=%s", profileName, "us-east-1")
+		return nil, newError(StepCreateClient, fmt.Errorf("failed to create org client with profile=%s, region=%s", profileName, "us-east-1"))
 	}
 	return svc, nil
 }
-
-func IsAccountExistsInOrg(ctx context.Context, svc *org.Client, accountId string) (bool, error) {
-	op, err := svc.ListAccounts(ctx, &org.ListAccountsInput{})
-	if err != nil {
-		return false, err
-	}
-	if op != nil {
-		for _, account := range op.Accounts {
-			if accountId == *account.Id && account.Status == "ACTIVE" {
-				return true, nil
-			}
-		}
-	}
-	return false, nil
-}
@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoleStatusMissingPolicies(t *testing.T) {
+	bucketPolicyArn := GetCloudtrailBucketPolicyArn("123456789012", "uptcloud")
+	if bucketPolicyArn != "arn:aws:iam::123456789012:policy/uptcloud-CloudtrailBucketPolicy" {
+		t.Fatalf("unexpected bucket policy ARN %s", bucketPolicyArn)
+	}
+
+	complete := &RoleStatus{
+		InlinePolicy: true,
+		AttachedPolicyArns: map[string]bool{
+			ViewOnlyAccessArn: true,
+			SecurityAuditArn:  true,
+			bucketPolicyArn:   true,
+		},
+	}
+	if missing := complete.MissingPolicies(bucketPolicyArn); len(missing) != 0 {
+		t.Errorf("expected no missing policies, got %v", missing)
+	}
+
+	partial := &RoleStatus{
+		AttachedPolicyArns: map[string]bool{
+			SecurityAuditArn: true,
+		},
+	}
+	expected := []string{ReadOnlyPolicyName, ViewOnlyAccessArn, bucketPolicyArn}
+	if missing := partial.MissingPolicies(bucketPolicyArn); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+	if missing := partial.MissingPolicies(""); len(missing) != 2 {
+		t.Errorf("expected the bucket policy to be ignored, got %v", missing)
+	}
+}
+
+func TestBucketPolicyLocation(t *testing.T) {
+	for _, c := range []struct {
+		bucketPrefix string
+		expected     string
+	}{
+		{bucketPrefix: "", expected: ""},
+		{bucketPrefix: "AWSLogs/", expected: "AWSLogs/"},
+		{bucketPrefix: "/AWSLogs/o-abcd1234/", expected: "AWSLogs/o-abcd1234/"},
+	} {
+		policy, err := ParsePolicyDocument(bucketPolicyDocument("example-log-archive", c.bucketPrefix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bucketName, bucketPrefix := bucketPolicyLocation(policy)
+		if bucketName != "example-log-archive" || bucketPrefix != c.expected {
+			t.Errorf("prefix %q: expected example-log-archive and %q, got %s and %q", c.bucketPrefix, c.expected, bucketName, bucketPrefix)
+		}
+	}
+	if bucketName, bucketPrefix := bucketPolicyLocation(&PolicyDocument{}); bucketName != "" || bucketPrefix != "" {
+		t.Errorf("expected no bucket in an empty policy, got %s and %q", bucketName, bucketPrefix)
+	}
+}
@@ -0,0 +1,274 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	storage "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+// bucketLocks serializes the read-modify-write cycles of the policy and of
+// the notification configuration of a bucket, since S3 cannot update them
+// conditionally. It only protects against the resources of the same
+// Terraform run.
+var bucketLocks sync.Map
+
+// lockBucket locks the setting, such as "policy", of the bucket and returns
+// the function unlocking it.
+func lockBucket(bucketName string, setting string) func() {
+	lock, _ := bucketLocks.LoadOrStore(bucketName+"/"+setting, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+var nonAlphanumeric = regexp.MustCompile("[^A-Za-z0-9]")
+
+// BucketGrantSid returns the statement ID tagging the grant of roleArn in a
+// bucket policy. It holds the account of the role, since the integration
+// roles of an organization share their name.
+func BucketGrantSid(roleArn string) string {
+	name := roleArn[strings.LastIndex(roleArn, "/")+1:]
+	account := ""
+	if match := principalArnPattern.FindStringSubmatch(roleArn); match != nil {
+		account = match[2]
+	}
+	return "UptycsCspmRead" + account + nonAlphanumeric.ReplaceAllString(name, "")
+}
+
+// BucketGrant is a statement of a bucket policy letting an integration role
+// of another account read the logs of the bucket.
+type BucketGrant struct {
+	Sid        string
+	BucketName string
+	RoleArn    string
+	Prefix     string
+}
+
+// Statement returns the policy statement of the grant.
+func (g BucketGrant) Statement() PolicyStatement {
+	bucketArn := "arn:aws:s3:::" + g.BucketName
+	return PolicyStatement{
+		Sid:       g.Sid,
+		Effect:    "Allow",
+		Principal: PolicyPrincipal{"AWS": StringList{g.RoleArn}},
+		Action:    StringList{"s3:GetBucketLocation", "s3:GetObject", "s3:ListBucket"},
+		Resource:  StringList{bucketArn, bucketArn + "/" + g.Prefix + "*"},
+	}
+}
+
+// grantFromStatement is the inverse of BucketGrant.Statement.
+func grantFromStatement(bucketName string, statement PolicyStatement) BucketGrant {
+	grant := BucketGrant{
+		Sid:        statement.Sid,
+		BucketName: bucketName,
+	}
+	if principals := statement.Principal["AWS"]; len(principals) == 1 {
+		grant.RoleArn = principals[0]
+	}
+	objects := "arn:aws:s3:::" + bucketName + "/"
+	for _, resource := range statement.Resource {
+		if strings.HasPrefix(resource, objects) {
+			grant.Prefix = strings.TrimSuffix(strings.TrimPrefix(resource, objects), "*")
+		}
+	}
+	return grant
+}
+
+// rawPolicy is a policy document whose statements are kept as written, so
+// that rewriting one statement leaves the others untouched.
+type rawPolicy struct {
+	fields     map[string]json.RawMessage
+	statements []json.RawMessage
+}
+
+func parseRawPolicy(document string) (*rawPolicy, error) {
+	policy := &rawPolicy{fields: make(map[string]json.RawMessage)}
+	if strings.TrimSpace(document) == "" {
+		policy.fields["Version"] = json.RawMessage(`"2012-10-17"`)
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(document), &policy.fields); err != nil {
+		return nil, err
+	}
+	if statement, ok := policy.fields["Statement"]; ok {
+		if trimmed := strings.TrimSpace(string(statement)); strings.HasPrefix(trimmed, "{") {
+			policy.statements = []json.RawMessage{statement}
+		} else if err := json.Unmarshal(statement, &policy.statements); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+func (p *rawPolicy) index(sid string) int {
+	for i, statement := range p.statements {
+		var tagged struct {
+			Sid string `json:"Sid"`
+		}
+		if err := json.Unmarshal(statement, &tagged); err == nil && tagged.Sid == sid {
+			return i
+		}
+	}
+	return -1
+}
+
+// statement returns the statement tagged sid, or nil.
+func (p *rawPolicy) statement(sid string) (*PolicyStatement, error) {
+	i := p.index(sid)
+	if i < 0 {
+		return nil, nil
+	}
+	var statement PolicyStatement
+	if err := json.Unmarshal(p.statements[i], &statement); err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// put replaces the statement with the same Sid, or appends it.
+func (p *rawPolicy) put(statement PolicyStatement) error {
+	raw, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+	if i := p.index(statement.Sid); i >= 0 {
+		p.statements[i] = raw
+	} else {
+		p.statements = append(p.statements, raw)
+	}
+	return nil
+}
+
+// remove removes the statement tagged sid and reports whether it was found.
+func (p *rawPolicy) remove(sid string) bool {
+	i := p.index(sid)
+	if i < 0 {
+		return false
+	}
+	p.statements = append(p.statements[:i], p.statements[i+1:]...)
+	return true
+}
+
+func (p *rawPolicy) String() (string, error) {
+	statements, err := json.Marshal(p.statements)
+	if err != nil {
+		return "", err
+	}
+	p.fields["Statement"] = statements
+	out, err := json.Marshal(p.fields)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func getBucketPolicy(ctx context.Context, svc *storage.Client, bucketName string) (*rawPolicy, error) {
+	out, err := svc.GetBucketPolicy(ctx, &storage.GetBucketPolicyInput{Bucket: &bucketName})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucketPolicy" {
+		return parseRawPolicy("")
+	}
+	if err != nil {
+		return nil, newError(StepGetBucketPolicy, err)
+	}
+	policy, err := parseRawPolicy(*out.Policy)
+	if err != nil {
+		return nil, newError(StepGetBucketPolicy, fmt.Errorf("invalid policy of bucket %s: %w", bucketName, err))
+	}
+	return policy, nil
+}
+
+// AddBucketGrant adds the grant to the policy of its bucket. It fails when
+// the policy already has a statement with the same Sid, which belongs to
+// someone else.
+func AddBucketGrant(ctx context.Context, svc *storage.Client, grant BucketGrant) error {
+	return putBucketGrant(ctx, svc, grant, false)
+}
+
+// PutBucketGrant adds the grant to the policy of its bucket, replacing the
+// statement with the same Sid, if any. The other statements are kept as
+// they are.
+func PutBucketGrant(ctx context.Context, svc *storage.Client, grant BucketGrant) error {
+	return putBucketGrant(ctx, svc, grant, true)
+}
+
+func putBucketGrant(ctx context.Context, svc *storage.Client, grant BucketGrant, replace bool) error {
+	unlock := lockBucket(grant.BucketName, "policy")
+	defer unlock()
+
+	policy, err := getBucketPolicy(ctx, svc, grant.BucketName)
+	if err != nil {
+		return err
+	}
+	if !replace && policy.index(grant.Sid) >= 0 {
+		return errAlreadyExists(StepPutBucketPolicy, "policy of bucket %s already has a statement %s", grant.BucketName, grant.Sid)
+	}
+	if err := policy.put(grant.Statement()); err != nil {
+		return newError(StepPutBucketPolicy, err)
+	}
+	document, err := policy.String()
+	if err != nil {
+		return newError(StepPutBucketPolicy, err)
+	}
+	_, err = svc.PutBucketPolicy(ctx, &storage.PutBucketPolicyInput{
+		Bucket: &grant.BucketName,
+		Policy: &document,
+	})
+	return newError(StepPutBucketPolicy, err)
+}
+
+// GetBucketGrant returns the grant tagged sid in the policy of the bucket,
+// or nil when the policy has no such statement.
+func GetBucketGrant(ctx context.Context, svc *storage.Client, bucketName string, sid string) (*BucketGrant, error) {
+	policy, err := getBucketPolicy(ctx, svc, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	statement, err := policy.statement(sid)
+	if err != nil {
+		return nil, newError(StepGetBucketPolicy, fmt.Errorf("invalid statement %s in policy of bucket %s: %w", sid, bucketName, err))
+	}
+	if statement == nil {
+		return nil, nil
+	}
+	grant := grantFromStatement(bucketName, *statement)
+	return &grant, nil
+}
+
+// DeleteBucketGrant removes the statement tagged sid from the policy of the
+// bucket, and deletes the policy when no other statement is left.
+func DeleteBucketGrant(ctx context.Context, svc *storage.Client, bucketName string, sid string) error {
+	unlock := lockBucket(bucketName, "policy")
+	defer unlock()
+
+	policy, err := getBucketPolicy(ctx, svc, bucketName)
+	if IsKind(err, ErrBucketNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !policy.remove(sid) {
+		return nil
+	}
+	if len(policy.statements) == 0 {
+		_, err := svc.DeleteBucketPolicy(ctx, &storage.DeleteBucketPolicyInput{Bucket: &bucketName})
+		return newError(StepDeleteS3Policy, err)
+	}
+	document, err := policy.String()
+	if err != nil {
+		return newError(StepPutBucketPolicy, err)
+	}
+	_, err = svc.PutBucketPolicy(ctx, &storage.PutBucketPolicyInput{
+		Bucket: &bucketName,
+		Policy: &document,
+	})
+	return newError(StepPutBucketPolicy, err)
+}
@@ -0,0 +1,164 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const logArchivePolicy = `{
+	"Version": "2012-10-17",
+	"Id": "LogArchive",
+	"Statement": [
+		{
+			"Sid": "AWSCloudTrailWrite",
+			"Effect": "Allow",
+			"Principal": {"Service": "cloudtrail.amazonaws.com"},
+			"Action": "s3:PutObject",
+			"Resource": "arn:aws:s3:::logs/AWSLogs/*",
+			"Condition": {"StringEquals": {"s3:x-amz-acl": "bucket-owner-full-control"}}
+		},
+		{
+			"Sid": "DenyInsecureTransport",
+			"Effect": "Deny",
+			"Principal": "*",
+			"Action": "s3:*",
+			"Resource": ["arn:aws:s3:::logs", "arn:aws:s3:::logs/*"],
+			"Condition": {"Bool": {"aws:SecureTransport": "false"}}
+		}
+	]
+}`
+
+func TestBucketGrantSid(t *testing.T) {
+	if sid := BucketGrantSid("arn:aws:iam::123456789012:role/path/Uptycs-Integration_1"); sid != "UptycsCspmRead123456789012UptycsIntegration1" {
+		t.Errorf("unexpected sid %s", sid)
+	}
+}
+
+func TestRawPolicyPutKeepsOtherStatements(t *testing.T) {
+	policy, err := parseRawPolicy(logArchivePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// statements are compacted when written back, but otherwise unchanged
+	var original []json.RawMessage
+	for _, statement := range policy.statements {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, statement); err != nil {
+			t.Fatal(err)
+		}
+		original = append(original, compacted.Bytes())
+	}
+
+	grant := BucketGrant{
+		Sid:        "UptycsCspmReadUptycsIntegration",
+		BucketName: "logs",
+		RoleArn:    "arn:aws:iam::123456789012:role/UptycsIntegration",
+		Prefix:     "AWSLogs/",
+	}
+	if err := policy.put(grant.Statement()); err != nil {
+		t.Fatal(err)
+	}
+	grant.Prefix = "AWSLogs/o-abcdef1234/"
+	if err := policy.put(grant.Statement()); err != nil {
+		t.Fatal(err)
+	}
+	document, err := policy.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := parseRawPolicy(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(merged.statements))
+	}
+	for i := range original {
+		if string(merged.statements[i]) != string(original[i]) {
+			t.Errorf("statement %d changed to %s", i, merged.statements[i])
+		}
+	}
+	if !strings.Contains(document, `"Id":"LogArchive"`) {
+		t.Errorf("policy Id was dropped: %s", document)
+	}
+	statement, err := merged.statement(grant.Sid)
+	if err != nil || statement == nil {
+		t.Fatalf("grant not found: %v", err)
+	}
+	if got := grantFromStatement("logs", *statement); !reflect.DeepEqual(got, grant) {
+		t.Errorf("unexpected grant %+v", got)
+	}
+
+	if !merged.remove(grant.Sid) || merged.remove(grant.Sid) {
+		t.Error("expected the grant to be removed exactly once")
+	}
+	if len(merged.statements) != 2 || string(merged.statements[0]) != string(original[0]) || string(merged.statements[1]) != string(original[1]) {
+		t.Errorf("unexpected statements after removal %s", merged.statements)
+	}
+}
+
+func TestRawPolicySingleStatement(t *testing.T) {
+	policy, err := parseRawPolicy(`{"Version": "2012-10-17", "Statement": {"Sid": "Only", "Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::logs/*"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.statements) != 1 || policy.index("Only") != 0 {
+		t.Fatalf("unexpected statements %s", policy.statements)
+	}
+
+	empty, err := parseRawPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.put(PolicyStatement{Sid: "Grant", Effect: "Allow"}); err != nil {
+		t.Fatal(err)
+	}
+	document, err := empty.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document != `{"Statement":[{"Sid":"Grant","Effect":"Allow"}],"Version":"2012-10-17"}` {
+		t.Errorf("unexpected document %s", document)
+	}
+}
+
+func TestBucketGrantRoundTrip(t *testing.T) {
+	grant := BucketGrant{
+		BucketName: "logs",
+		RoleArn:    "arn:aws:iam::123456789012:role/uptcloud",
+	}
+	grant.Sid = BucketGrantSid(grant.RoleArn)
+
+	policy, err := parseRawPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := policy.put(grant.Statement()); err != nil {
+		t.Fatal(err)
+	}
+	document, err := policy.String()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := parseRawPolicy(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement, err := parsed.statement(grant.Sid)
+	if err != nil || statement == nil {
+		t.Fatalf("grant not found in %s: %v", document, err)
+	}
+	if got := grantFromStatement("logs", *statement); !reflect.DeepEqual(got, grant) {
+		t.Errorf("expected %+v, got %+v", grant, got)
+	}
+	if missing, err := parsed.statement("Other"); err != nil || missing != nil {
+		t.Errorf("expected no statement for another sid, got %+v, %v", missing, err)
+	}
+	if !parsed.remove(grant.Sid) || len(parsed.statements) != 0 {
+		t.Errorf("expected the policy to be left without statements, got %s", parsed.statements)
+	}
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	storage "github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// getBucketRegion returns the region of the bucket, as GetBucketLocation
+// reports it with the legacy values of us-east-1 and eu-west-1 mapped.
+func getBucketRegion(ctx context.Context, svc *storage.Client, bucketName string) (string, error) {
+	out, err := svc.GetBucketLocation(ctx, &storage.GetBucketLocationInput{Bucket: &bucketName})
+	if err != nil {
+		return "", newError(StepValidateBucket, err)
+	}
+	switch out.LocationConstraint {
+	case "":
+		return "us-east-1", nil
+	case s3types.BucketLocationConstraintEu:
+		return "eu-west-1", nil
+	}
+	return string(out.LocationConstraint), nil
+}
+
+// checkBucketRegion returns an ErrBucketWrongRegion error when the bucket is
+// not in bucketRegion.
+func checkBucketRegion(ctx context.Context, svc *storage.Client, bucketName string, bucketRegion string) error {
+	region, err := getBucketRegion(ctx, svc, bucketName)
+	if err != nil {
+		return err
+	}
+	if region != bucketRegion {
+		return &Error{
+			Kind: ErrBucketWrongRegion,
+			Step: StepValidateBucket,
+			Err:  fmt.Errorf("bucket %s is in %s, not in bucket_region %s", bucketName, region, bucketRegion),
+		}
+	}
+	return nil
+}
+
+// ValidateCloudTrailBucket inspects the CloudTrail bucket Uptycs reads the
+// logs of accountID from, under bucketPrefix, and returns a problem per check
+// that failed: the region of the bucket must be bucketRegion, its logs should
+// not be encrypted with KMS keys the integration role cannot use, and it
+// should hold CloudTrail logs of the account. An error is returned only when
+// the bucket cannot be found. Checks the caller is not allowed to run are
+// skipped.
+func ValidateCloudTrailBucket(ctx context.Context, svc *storage.Client, bucketName string, bucketRegion string, bucketPrefix string, accountID string) ([]*Error, error) {
+	if err := checkBucketRegion(ctx, svc, bucketName, bucketRegion); err != nil {
+		if IsKind(err, ErrBucketWrongRegion) {
+			// The other requests would be redirected to the right region.
+			awsErr, _ := AsError(err)
+			return []*Error{awsErr}, nil
+		}
+		if isAccessDenied(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var problems []*Error
+	logKey, logsProblem := findTrailLog(ctx, svc, bucketName, bucketPrefix, accountID)
+	if problem := checkLogEncryption(ctx, svc, bucketName, logKey); problem != nil {
+		problems = append(problems, problem)
+	}
+	if logsProblem != nil {
+		problems = append(problems, logsProblem)
+	}
+	return problems, nil
+}
+
+// checkLogEncryption reports logs encrypted with KMS, as the integration role
+// then also needs kms:Decrypt on the key. A trail encrypts its logs with its
+// own key, so the encryption of logKey, a log object, is checked. Without
+// one, the default encryption of the bucket is.
+func checkLogEncryption(ctx context.Context, svc *storage.Client, bucketName string, logKey string) *Error {
+	if logKey == "" {
+		return checkBucketEncryption(ctx, svc, bucketName)
+	}
+	out, err := svc.HeadObject(ctx, &storage.HeadObjectInput{Bucket: &bucketName, Key: &logKey})
+	if err != nil {
+		return checkBucketEncryption(ctx, svc, bucketName)
+	}
+	return kmsEncryptionProblem(fmt.Sprintf("CloudTrail logs of bucket %s, such as %s, are", bucketName, logKey),
+		out.ServerSideEncryption, aws.ToString(out.SSEKMSKeyId))
+}
+
+// checkBucketEncryption reports a bucket whose default encryption uses KMS.
+func checkBucketEncryption(ctx context.Context, svc *storage.Client, bucketName string) *Error {
+	out, err := svc.GetBucketEncryption(ctx, &storage.GetBucketEncryptionInput{Bucket: &bucketName})
+	if err != nil || out.ServerSideEncryptionConfiguration == nil {
+		// No default encryption, or not allowed to read it.
+		return nil
+	}
+	for _, rule := range out.ServerSideEncryptionConfiguration.Rules {
+		byDefault := rule.ApplyServerSideEncryptionByDefault
+		if byDefault == nil {
+			continue
+		}
+		if problem := kmsEncryptionProblem(fmt.Sprintf("new objects of bucket %s are", bucketName),
+			byDefault.SSEAlgorithm, aws.ToString(byDefault.KMSMasterKeyID)); problem != nil {
+			return problem
+		}
+	}
+	return nil
+}
+
+// kmsEncryptionProblem returns an ErrBucketKmsEncrypted problem when algorithm
+// uses KMS, nil otherwise. what describes the encrypted objects.
+func kmsEncryptionProblem(what string, algorithm s3types.ServerSideEncryption, key string) *Error {
+	switch algorithm {
+	case s3types.ServerSideEncryptionAwsKms, s3types.ServerSideEncryptionAwsKmsDsse:
+	default:
+		return nil
+	}
+	if key == "" {
+		key = "aws/s3, an AWS managed key that cannot be shared with other accounts"
+	}
+	return &Error{
+		Kind: ErrBucketKmsEncrypted,
+		Step: StepValidateBucket,
+		Err:  fmt.Errorf("%s encrypted with %s using KMS key %s", what, algorithm, key),
+	}
+}
+
+// findTrailLog returns the key of a CloudTrail log of the account under
+// bucketPrefix, or reports a bucket without one. The logs are looked for
+// under AWSLogs/<account>/CloudTrail and under the
+// AWSLogs/<organization>/<account>/CloudTrail layout of organization trails,
+// at the root of the bucket, under bucketPrefix and, without bucketPrefix,
+// under the key prefix of a trail, such as <prefix>/AWSLogs.
+func findTrailLog(ctx context.Context, svc *storage.Client, bucketName string, bucketPrefix string, accountID string) (string, *Error) {
+	var roots []string
+	if i := strings.Index(bucketPrefix, "AWSLogs/"); i >= 0 {
+		roots = append(roots, bucketPrefix[:i+len("AWSLogs/")])
+	} else {
+		base := bucketPrefix
+		if base != "" && !strings.HasSuffix(base, "/") {
+			base += "/"
+		}
+		roots = append(roots, base+"AWSLogs/")
+		if bucketPrefix == "" {
+			prefixes, err := listCommonPrefixes(ctx, svc, bucketName, "")
+			if err != nil {
+				return "", nil
+			}
+			for _, prefix := range prefixes {
+				if prefix != "AWSLogs/" {
+					roots = append(roots, prefix+"AWSLogs/")
+				}
+			}
+		}
+	}
+
+	var candidates []string
+	for _, root := range roots {
+		candidates = append(candidates, root+accountID+"/CloudTrail/")
+		prefixes, err := listCommonPrefixes(ctx, svc, bucketName, root)
+		if err != nil {
+			return "", nil
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(strings.TrimPrefix(prefix, root), "o-") {
+				candidates = append(candidates, prefix+accountID+"/CloudTrail/")
+			}
+		}
+	}
+
+	var searched []string
+	for _, candidate := range candidates {
+		// the role can only read the logs under bucketPrefix
+		if !strings.HasPrefix(candidate, bucketPrefix) {
+			continue
+		}
+		searched = append(searched, candidate)
+		out, err := svc.ListObjectsV2(ctx, &storage.ListObjectsV2Input{
+			Bucket:  &bucketName,
+			Prefix:  aws.String(candidate),
+			MaxKeys: aws.Int32(1),
+		})
+		if err != nil {
+			return "", nil
+		}
+		if len(out.Contents) > 0 {
+			return aws.ToString(out.Contents[0].Key), nil
+		}
+	}
+	return "", &Error{
+		Kind: ErrBucketNoTrailLogs,
+		Step: StepValidateBucket,
+		Err:  fmt.Errorf("bucket %s has no CloudTrail logs of account %s under %s", bucketName, accountID, strings.Join(searched, " or ")),
+	}
+}
+
+// listCommonPrefixes returns the first page of the "directories" right under
+// prefix.
+func listCommonPrefixes(ctx context.Context, svc *storage.Client, bucketName string, prefix string) ([]string, error) {
+	out, err := svc.ListObjectsV2(ctx, &storage.ListObjectsV2Input{
+		Bucket:    &bucketName,
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	prefixes := make([]string, 0, len(out.CommonPrefixes))
+	for _, common := range out.CommonPrefixes {
+		prefixes = append(prefixes, aws.ToString(common.Prefix))
+	}
+	return prefixes, nil
+}
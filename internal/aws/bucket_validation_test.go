@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	storage "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeBucket is an httptest stand-in for the S3 API of a single bucket.
+type fakeBucket struct {
+	location   string
+	encryption string
+	keys       []string
+	// logsKmsKey, when set, is the KMS key of every object.
+	logsKmsKey string
+}
+
+func (b fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodHead:
+		key := strings.TrimPrefix(r.URL.Path, "/logs/")
+		for _, k := range b.keys {
+			if k != key {
+				continue
+			}
+			if b.logsKmsKey != "" {
+				w.Header().Set("x-amz-server-side-encryption", "aws:kms")
+				w.Header().Set("x-amz-server-side-encryption-aws-kms-key-id", b.logsKmsKey)
+			} else {
+				w.Header().Set("x-amz-server-side-encryption", "AES256")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case query.Has("location"):
+		fmt.Fprintf(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">%s</LocationConstraint>`, b.location)
+	case query.Has("encryption"):
+		if b.encryption == "" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>ServerSideEncryptionConfigurationNotFoundError</Code></Error>`)
+			return
+		}
+		fmt.Fprint(w, b.encryption)
+	case query.Get("list-type") == "2":
+		prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+		var contents, prefixes strings.Builder
+		count, seen := 0, map[string]bool{}
+		for _, key := range b.keys {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			if rest := strings.TrimPrefix(key, prefix); delimiter != "" && strings.Contains(rest, delimiter) {
+				common := prefix + rest[:strings.Index(rest, delimiter)+1]
+				if !seen[common] {
+					seen[common] = true
+					fmt.Fprintf(&prefixes, "<CommonPrefixes><Prefix>%s</Prefix></CommonPrefixes>", common)
+				}
+				continue
+			}
+			count++
+			fmt.Fprintf(&contents, "<Contents><Key>%s</Key></Contents>", key)
+		}
+		fmt.Fprintf(w, `<ListBucketResult><KeyCount>%d</KeyCount>%s%s</ListBucketResult>`, count, contents.String(), prefixes.String())
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newFakeS3Client(t *testing.T, bucket fakeBucket) *storage.Client {
+	t.Helper()
+	server := httptest.NewServer(bucket)
+	t.Cleanup(server.Close)
+	return storage.New(storage.Options{
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		HTTPClient:   server.Client(),
+	})
+}
+
+func TestValidateCloudTrailBucket(t *testing.T) {
+	const account = "123456789012"
+	kms := `<ServerSideEncryptionConfiguration><Rule><ApplyServerSideEncryptionByDefault><SSEAlgorithm>aws:kms</SSEAlgorithm>` +
+		`<KMSMasterKeyID>arn:aws:kms:us-east-1:123456789012:key/abc</KMSMasterKeyID></ApplyServerSideEncryptionByDefault></Rule></ServerSideEncryptionConfiguration>`
+	cases := []struct {
+		name   string
+		bucket fakeBucket
+		region string
+		prefix string
+		kinds  []ErrorKind
+	}{
+		{"account trail", fakeBucket{keys: []string{"AWSLogs/" + account + "/CloudTrail/us-east-1/log.json.gz"}}, "us-east-1", "", nil},
+		{"organization trail", fakeBucket{location: "eu-west-2", keys: []string{"AWSLogs/o-abc/" + account + "/CloudTrail/log.json.gz"}}, "eu-west-2", "", nil},
+		{"legacy EU location", fakeBucket{location: "EU", keys: []string{"AWSLogs/" + account + "/CloudTrail/log.json.gz"}}, "eu-west-1", "", nil},
+		{"wrong region", fakeBucket{location: "eu-west-1"}, "us-east-1", "", []ErrorKind{ErrBucketWrongRegion}},
+		{"kms and no logs", fakeBucket{encryption: kms, keys: []string{"AWSLogs/999999999999/CloudTrail/log.json.gz"}}, "us-east-1", "", []ErrorKind{ErrBucketKmsEncrypted, ErrBucketNoTrailLogs}},
+		{"trail with a key prefix", fakeBucket{keys: []string{"trails/AWSLogs/o-abc/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "", nil},
+		{"bucket prefix", fakeBucket{keys: []string{"trails/AWSLogs/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "trails", nil},
+		{"bucket prefix of an organization trail", fakeBucket{keys: []string{"trails/AWSLogs/o-abc/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "trails/AWSLogs/o-abc/", nil},
+		{"logs outside the bucket prefix", fakeBucket{keys: []string{"AWSLogs/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "trails/", []ErrorKind{ErrBucketNoTrailLogs}},
+		{"logs encrypted by the trail", fakeBucket{logsKmsKey: "arn:aws:kms:us-east-1:123456789012:key/abc", keys: []string{"AWSLogs/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "", []ErrorKind{ErrBucketKmsEncrypted}},
+		{"logs older than the default encryption", fakeBucket{encryption: kms, keys: []string{"AWSLogs/" + account + "/CloudTrail/log.json.gz"}}, "us-east-1", "", nil},
+	}
+	for _, c := range cases {
+		problems, err := ValidateCloudTrailBucket(context.Background(), newFakeS3Client(t, c.bucket), "logs", c.region, c.prefix, account)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if len(problems) != len(c.kinds) {
+			t.Errorf("%s: expected %d problems, got %v", c.name, len(c.kinds), problems)
+			continue
+		}
+		for i, problem := range problems {
+			if problem.Kind != c.kinds[i] || problem.Step != StepValidateBucket {
+				t.Errorf("%s: unexpected problem %d: %v", c.name, i, problem)
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// Features of the Uptycs read-only policy. Each one enables a set of
+// statements from the catalog below.
+const (
+	FeatureCSPM                      = "cspm"
+	FeatureCIEM                      = "ciem"
+	FeatureDSPM                      = "dspm"
+	FeatureAgentlessScanning         = "agentless_scanning"
+	FeatureContainerRegistryScanning = "container_registry_scanning"
+)
+
+// MaxInlinePolicySize is the IAM quota for the inline policies of a role,
+// counted without whitespace.
+const MaxInlinePolicySize = 10240
+
+// policyCatalog lists, per feature, the permissions Uptycs needs on top of
+// the ViewOnlyAccess and SecurityAudit managed policies.
+var policyCatalog = map[string][]PolicyStatement{
+	FeatureCSPM: {
+		{
+			Sid:    "UptycsCspmReadOnly",
+			Effect: "Allow",
+			Action: StringList{
+				"apigateway:GET",
+				"backup:ListBackupVaults",
+				"backup:ListBackupPlans",
+				"cloudtrail:GetEventSelectors",
+				"cloudtrail:GetInsightSelectors",
+				"cloudtrail:GetTrailStatus",
+				"codebuild:BatchGetProjects",
+				"ec2:GetEbsEncryptionByDefault",
+				"ecr:DescribeImageScanFindings",
+				"eks:DescribeCluster",
+				"eks:ListClusters",
+				"elasticfilesystem:DescribeFileSystemPolicy",
+				"glue:GetSecurityConfigurations",
+				"lambda:GetFunctionConfiguration",
+				"lambda:GetPolicy",
+				"s3:GetBucketPolicyStatus",
+				"s3:GetAccountPublicAccessBlock",
+				"ses:GetIdentityPolicies",
+				"sns:GetSubscriptionAttributes",
+				"sqs:GetQueueAttributes",
+				"ssm:GetDocument",
+				"ssm:ListDocuments",
+			},
+			Resource: StringList{"*"},
+		},
+	},
+	FeatureCIEM: {
+		{
+			Sid:    "UptycsCiemReadOnly",
+			Effect: "Allow",
+			Action: StringList{
+				"access-analyzer:GetAnalyzer",
+				"access-analyzer:ListAnalyzers",
+				"access-analyzer:ListFindings",
+				"iam:GenerateServiceLastAccessedDetails",
+				"iam:GetAccountAuthorizationDetails",
+				"iam:GetServiceLastAccessedDetails",
+				"iam:SimulatePrincipalPolicy",
+				"identitystore:ListGroupMemberships",
+				"identitystore:ListGroups",
+				"identitystore:ListUsers",
+				"organizations:DescribeOrganization",
+				"organizations:ListPolicies",
+				"organizations:ListPoliciesForTarget",
+				"sso:DescribePermissionSet",
+				"sso:ListAccountAssignments",
+				"sso:ListInstances",
+				"sso:ListPermissionSets",
+			},
+			Resource: StringList{"*"},
+		},
+	},
+	FeatureDSPM: {
+		{
+			Sid:    "UptycsDspmReadOnly",
+			Effect: "Allow",
+			Action: StringList{
+				"dynamodb:DescribeTable",
+				"dynamodb:ListTables",
+				"macie2:GetClassificationScope",
+				"macie2:ListClassificationJobs",
+				"rds:DescribeDBSnapshots",
+				"s3:GetObject",
+				"s3:ListBucket",
+			},
+			Resource: StringList{"*"},
+		},
+	},
+	FeatureAgentlessScanning: {
+		{
+			Sid:    "UptycsAgentlessSnapshots",
+			Effect: "Allow",
+			Action: StringList{
+				"ebs:GetSnapshotBlock",
+				"ebs:ListChangedBlocks",
+				"ebs:ListSnapshotBlocks",
+				"ec2:CreateSnapshot",
+				"ec2:DescribeSnapshots",
+			},
+			Resource: StringList{"*"},
+		},
+		{
+			Sid:      "UptycsAgentlessSharing",
+			Effect:   "Allow",
+			Action:   StringList{"ec2:CopySnapshot", "ec2:ModifySnapshotAttribute"},
+			Resource: StringList{"*"},
+			Condition: map[string]map[string]StringList{
+				"StringEquals": {"aws:ResourceTag/CreatedBy": {"Uptycs"}},
+			},
+		},
+		{
+			Sid:      "UptycsAgentlessTagging",
+			Effect:   "Allow",
+			Action:   StringList{"ec2:CreateTags"},
+			Resource: StringList{"arn:aws:ec2:*::snapshot/*"},
+			Condition: map[string]map[string]StringList{
+				"StringEquals": {"ec2:CreateAction": {"CreateSnapshot", "CopySnapshot"}},
+			},
+		},
+		{
+			Sid:      "UptycsAgentlessCleanup",
+			Effect:   "Allow",
+			Action:   StringList{"ec2:DeleteSnapshot"},
+			Resource: StringList{"*"},
+			Condition: map[string]map[string]StringList{
+				"StringEquals": {"aws:ResourceTag/CreatedBy": {"Uptycs"}},
+			},
+		},
+		{
+			// Grants are only allowed for EC2 itself, the other actions do
+			// not have the kms:GrantIsForAWSResource key and are allowed
+			// by UptycsAgentlessKmsDecrypt.
+			Sid:      "UptycsAgentlessKms",
+			Effect:   "Allow",
+			Action:   StringList{"kms:CreateGrant"},
+			Resource: StringList{"*"},
+			Condition: map[string]map[string]StringList{
+				"StringLike": {"kms:ViaService": {"ec2.*.amazonaws.com"}},
+				"Bool":       {"kms:GrantIsForAWSResource": {"true"}},
+			},
+		},
+		{
+			Sid:      "UptycsAgentlessKmsDecrypt",
+			Effect:   "Allow",
+			Action:   StringList{"kms:Decrypt", "kms:DescribeKey", "kms:ReEncrypt*"},
+			Resource: StringList{"*"},
+			Condition: map[string]map[string]StringList{
+				"StringLike": {"kms:ViaService": {"ec2.*.amazonaws.com"}},
+			},
+		},
+	},
+	FeatureContainerRegistryScanning: {
+		{
+			Sid:    "UptycsContainerRegistryReadOnly",
+			Effect: "Allow",
+			Action: StringList{
+				"ecr-public:DescribeImages",
+				"ecr-public:DescribeRepositories",
+				"ecr:BatchGetImage",
+				"ecr:DescribeImages",
+				"ecr:DescribeRepositories",
+				"ecr:GetAuthorizationToken",
+				"ecr:GetDownloadUrlForLayer",
+				"ecr:ListImages",
+			},
+			Resource: StringList{"*"},
+		},
+	},
+}
+
+// catalogOrder is the order in which feature statements are rendered.
+var catalogOrder = []string{
+	FeatureCSPM,
+	FeatureCIEM,
+	FeatureDSPM,
+	FeatureAgentlessScanning,
+	FeatureContainerRegistryScanning,
+}
+
+// BuildReadOnlyPolicy renders the Uptycs read-only policy for the enabled
+// features, followed by the extra statements.
+func BuildReadOnlyPolicy(features map[string]bool, extra []PolicyStatement) (*PolicyDocument, error) {
+	for feature := range features {
+		if _, found := policyCatalog[feature]; !found {
+			return nil, fmt.Errorf("unknown policy feature %q", feature)
+		}
+	}
+	policy := &PolicyDocument{Version: "2012-10-17"}
+	for _, feature := range catalogOrder {
+		if features[feature] {
+			policy.Statement = append(policy.Statement, policyCatalog[feature]...)
+		}
+	}
+	policy.Statement = append(policy.Statement, extra...)
+	sids := make(map[string]bool, len(policy.Statement))
+	for _, statement := range policy.Statement {
+		if statement.Sid == "" {
+			continue
+		}
+		if sids[statement.Sid] {
+			return nil, fmt.Errorf("statement ID %q is used more than once, statement IDs must be unique in a policy", statement.Sid)
+		}
+		sids[statement.Sid] = true
+	}
+	if len(policy.Statement) == 0 {
+		return nil, fmt.Errorf("the policy has no statements, enable a feature or add a statement")
+	}
+	return policy, nil
+}
+
+// LogQueueStatement returns the statement letting the integration role
+// consume the log notification queues.
+func LogQueueStatement(queueArns []string) PolicyStatement {
+	return PolicyStatement{
+		Sid:      "UptycsLogNotifications",
+		Effect:   "Allow",
+		Action:   LogQueueActions,
+		Resource: queueArns,
+	}
+}
+
+// Canonical returns the policy as compact JSON, with the actions and
+// resources of each statement sorted and de-duplicated, so that equivalent
+// policies always render the same way.
+func (p *PolicyDocument) Canonical() (string, error) {
+	canonical := PolicyDocument{
+		Version: p.Version,
+		ID:      p.ID,
+	}
+	for _, statement := range p.Statement {
+		statement.Action = sortedUnique(statement.Action)
+		statement.NotAction = sortedUnique(statement.NotAction)
+		statement.Resource = sortedUnique(statement.Resource)
+		statement.NotResource = sortedUnique(statement.NotResource)
+		canonical.Statement = append(canonical.Statement, statement)
+	}
+	out, err := json.Marshal(canonical)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// CheckInlinePolicySize returns an error when document exceeds the size
+// IAM allows for the inline policies of a role.
+func CheckInlinePolicySize(document string) error {
+	if size := PolicySize(document); size > MaxInlinePolicySize {
+		return fmt.Errorf("policy is %d characters long, IAM allows at most %d for the inline policies of a role", size, MaxInlinePolicySize)
+	}
+	return nil
+}
+
+// PolicySize returns the size of document as counted by IAM, which ignores
+// whitespace.
+func PolicySize(document string) int {
+	size := 0
+	for _, r := range document {
+		if !unicode.IsSpace(r) {
+			size++
+		}
+	}
+	return size
+}
+
+func sortedUnique(values StringList) StringList {
+	if len(values) == 0 {
+		return values
+	}
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		seen[value] = true
+	}
+	unique := make(StringList, 0, len(seen))
+	for value := range seen {
+		unique = append(unique, value)
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,350 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrorKind classifies an AWS failure so callers can react to it and report
+// it to the practitioner with a useful remediation.
+type ErrorKind int
+
+const (
+	ErrUnknown ErrorKind = iota
+	ErrAssumeRoleDenied
+	ErrSCPDenied
+	ErrMissingProfile
+	ErrThrottled
+	ErrNoSuchEntity
+	ErrEntityAlreadyExists
+	ErrBucketNotFound
+	ErrBucketWrongRegion
+	ErrTimeout
+	ErrAccountMismatch
+	ErrTrustMisconfigured
+	ErrTrustVerificationFailed
+	ErrBucketKmsEncrypted
+	ErrBucketNoTrailLogs
+	ErrAlreadyExists
+)
+
+// Step names used to report which part of an operation failed.
+const (
+	StepLoadConfig         = "load AWS configuration"
+	StepGetRole            = "get integration role"
+	StepCreateRole         = "create integration role"
+	StepDeleteRole         = "delete integration role"
+	StepListPolicies       = "list role policies"
+	StepPutInlinePolicy    = "put inline read-only policy"
+	StepDeleteInlinePolicy = "delete inline read-only policy"
+	StepGetInlinePolicy    = "get inline read-only policy"
+	StepGetManagedPolicy   = "get managed policy"
+	StepAttachPolicy       = "attach managed policy"
+	StepDetachPolicy       = "detach managed policy"
+	StepCreateBucketPolicy = "create CloudTrail bucket policy"
+	StepDeleteBucketPolicy = "delete CloudTrail bucket policy"
+	StepValidateBucket     = "validate CloudTrail bucket"
+	StepListOrgAccounts    = "list organization accounts"
+	StepListOrgTree        = "list organizational units"
+	StepListOrgTags        = "list account tags"
+	StepCreateClient       = "create AWS client"
+	StepGetCallerIdentity  = "get caller identity"
+	StepDescribeOrg        = "describe organization"
+	StepCreateKey          = "create KMS key"
+	StepPutKeyPolicy       = "put policy of KMS key"
+	StepDeleteKey          = "schedule KMS key deletion"
+	StepCreateBucket       = "create CloudTrail bucket"
+	StepDeleteBucket       = "delete CloudTrail bucket"
+	StepPutBucketPolicy    = "put policy of CloudTrail bucket"
+	StepCreateTrail        = "create organization trail"
+	StepStartLogging       = "start trail logging"
+	StepGetTrail           = "get organization trail"
+	StepDeleteTrail        = "delete organization trail"
+	StepGetBucketPolicy    = "get policy of CloudTrail bucket"
+	StepDeleteS3Policy     = "delete policy of CloudTrail bucket"
+	StepGetNotifications   = "get notification configuration of CloudTrail bucket"
+	StepPutNotifications   = "put notification configuration of CloudTrail bucket"
+	StepCreateTopic        = "create log notification topic"
+	StepGetTopic           = "get log notification topic"
+	StepDeleteTopic        = "delete log notification topic"
+	StepCreateQueue        = "create log notification queue"
+	StepGetQueue           = "get log notification queue"
+	StepSetQueuePolicy     = "set log notification queue policy"
+	StepDeleteQueue        = "delete log notification queue"
+	StepSubscribeQueue     = "subscribe log notification queue"
+	StepPutRule            = "put event forwarding rule"
+	StepPutTargets         = "put event forwarding target"
+	StepGetRule            = "get event forwarding rule"
+	StepDeleteRule         = "delete event forwarding rule"
+	StepVerifyTrust        = "verify trust policy of integration role"
+	StepVerifyAssumeRole   = "assume integration role as verifier"
+	StepSimulatePolicy     = "simulate integration role permissions"
+	StepUpdateTrust        = "update trust policy of integration role"
+)
+
+// Error is returned by this package for every failed AWS operation. It keeps
+// the step that was being executed along with the classified kind.
+type Error struct {
+	Kind ErrorKind
+	Step string
+	Err  error
+
+	// CleanupErr is set when rolling back the resources created before the
+	// failure did not complete.
+	CleanupErr error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Summary is a short, human readable description of the failure.
+func (e *Error) Summary() string {
+	switch e.Kind {
+	case ErrAssumeRoleDenied:
+		return "Assume Role Denied"
+	case ErrSCPDenied:
+		return "Denied By Service Control Policy"
+	case ErrMissingProfile:
+		return "AWS Profile Not Found"
+	case ErrThrottled:
+		return "AWS Request Throttled"
+	case ErrNoSuchEntity:
+		return "IAM Entity Not Found"
+	case ErrEntityAlreadyExists:
+		return "IAM Entity Already Exists"
+	case ErrBucketNotFound:
+		return "CloudTrail Bucket Not Found"
+	case ErrBucketWrongRegion:
+		return "CloudTrail Bucket Region Mismatch"
+	case ErrTimeout:
+		return "AWS Operation Timed Out"
+	case ErrAccountMismatch:
+		return "Caller Account Mismatch"
+	case ErrTrustMisconfigured:
+		return "Trust Policy Misconfigured"
+	case ErrTrustVerificationFailed:
+		return "Trust Verification Failed"
+	case ErrBucketKmsEncrypted:
+		return "CloudTrail Bucket Encrypted With KMS"
+	case ErrBucketNoTrailLogs:
+		return "No CloudTrail Logs In Bucket"
+	case ErrAlreadyExists:
+		return "AWS Resource Already Exists"
+	}
+	return "AWS Error"
+}
+
+// Remediation is a hint describing how the practitioner can fix the failure.
+func (e *Error) Remediation() string {
+	switch e.Kind {
+	case ErrAssumeRoleDenied:
+		return "Check that the profile's principal is allowed to call sts:AssumeRole on the organization access role " +
+			"of the target account, and that the role's trust policy trusts that principal."
+	case ErrSCPDenied:
+		return "A service control policy attached to the account or one of its organizational units explicitly " +
+			"denies this action. Ask the organization administrator to exempt the integration from the policy."
+	case ErrMissingProfile:
+		return "Check that profile_name matches a profile defined in the shared AWS config or credentials file."
+	case ErrThrottled:
+		return "AWS throttled the request. Retry the operation later or reduce the apply parallelism."
+	case ErrNoSuchEntity:
+		return "The IAM entity does not exist in the target account. It may have been deleted outside of Terraform."
+	case ErrEntityAlreadyExists:
+		return "An IAM entity with the same name already exists in the target account. Import it into the " +
+			"Terraform state or choose a different integration_name."
+	case ErrBucketNotFound:
+		return "Check that bucket_name refers to an existing bucket that is reachable from the target account."
+	case ErrBucketWrongRegion:
+		return "The bucket exists in a different region. Set bucket_region to the region the bucket was created in."
+	case ErrTimeout:
+		return "The operation did not finish within its timeout. Increase the matching value in the timeouts block " +
+			"or check the connectivity to AWS."
+	case ErrAccountMismatch:
+		return "use_caller_credentials requires the credentials of profile_name to belong to account_id. " +
+			"Use a profile for that account or let the provider assume the organization access role instead."
+	case ErrTrustMisconfigured:
+		return "Uptycs would not be able to assume the role. Check upt_account_id and external_id against the values " +
+			"shown in the Uptycs console, and that the trust policy of the role was not edited outside of Terraform."
+	case ErrTrustVerificationFailed:
+		return "The verifier could not assume the role as Uptycs would, or could assume it without the external ID. " +
+			"Check that verifier_profile_name has credentials of a principal of upt_account_id allowed to call sts:AssumeRole."
+	case ErrBucketKmsEncrypted:
+		return "Uptycs can only read the logs if the integration role is allowed kms:Decrypt on the key, in the key policy " +
+			"and in the policy_document of the role. Logs encrypted with an AWS managed key cannot be read from other accounts."
+	case ErrBucketNoTrailLogs:
+		return "Check that bucket_name is the bucket a trail of the account delivers to, and bucket_prefix the key prefix of the trail. A new trail delivers its first logs " +
+			"within about 15 minutes, this warning then goes away."
+	case ErrAlreadyExists:
+		return "A resource with the same name was created outside of this Terraform resource and is left untouched. " +
+			"Import it into the Terraform state where supported, choose a different name, or remove it if nothing uses it."
+	}
+	return "Inspect the error returned by AWS for more details."
+}
+
+// newError wraps err as an *Error for the given step. It returns nil when err
+// is nil and keeps the original step when err is already classified.
+func newError(step string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return wrapError(step, err)
+}
+
+func wrapError(step string, err error) *Error {
+	var awsErr *Error
+	if errors.As(err, &awsErr) {
+		return awsErr
+	}
+	return &Error{
+		Kind: classify(err),
+		Step: step,
+		Err:  err,
+	}
+}
+
+// errAlreadyExists reports, at step, a resource found where this provider
+// was about to create its own.
+func errAlreadyExists(step string, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind: ErrAlreadyExists,
+		Step: step,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// AsError returns the *Error from err's chain, if any.
+func AsError(err error) (*Error, bool) {
+	var awsErr *Error
+	if errors.As(err, &awsErr) {
+		return awsErr, true
+	}
+	return nil, false
+}
+
+// IsKind reports whether err was classified as kind.
+func IsKind(err error, kind ErrorKind) bool {
+	if awsErr, ok := AsError(err); ok {
+		return awsErr.Kind == kind
+	}
+	return classify(err) == kind
+}
+
+func classify(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	var profileErr config.SharedConfigProfileNotExistError
+	if errors.As(err, &profileErr) {
+		return ErrMissingProfile
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && strings.Contains(apiErr.ErrorMessage(), "service control policy") {
+		return ErrSCPDenied
+	}
+
+	if isAssumeRoleFailure(err) {
+		return ErrAssumeRoleDenied
+	}
+
+	var noSuchEntity *iamtypes.NoSuchEntityException
+	if errors.As(err, &noSuchEntity) {
+		return ErrNoSuchEntity
+	}
+	var alreadyExists *iamtypes.EntityAlreadyExistsException
+	if errors.As(err, &alreadyExists) {
+		return ErrEntityAlreadyExists
+	}
+
+	var noSuchBucket *s3types.NoSuchBucket
+	var notFound *s3types.NotFound
+	if errors.As(err, &noSuchBucket) || errors.As(err, &notFound) {
+		return ErrBucketNotFound
+	}
+
+	if apiErr != nil {
+		switch apiErr.ErrorCode() {
+		case "NoSuchEntity":
+			return ErrNoSuchEntity
+		case "EntityAlreadyExists":
+			return ErrEntityAlreadyExists
+		case "NoSuchBucket", "NotFound":
+			return ErrBucketNotFound
+		case "PermanentRedirect", "AuthorizationHeaderMalformed", "IllegalLocationConstraintException":
+			return ErrBucketWrongRegion
+		}
+		if isThrottleCode(apiErr) {
+			return ErrThrottled
+		}
+	}
+
+	var respErr *smithyhttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusMovedPermanently:
+			return ErrBucketWrongRegion
+		case http.StatusTooManyRequests:
+			return ErrThrottled
+		}
+	}
+	return ErrUnknown
+}
+
+// isAssumeRoleFailure reports whether the credentials for the request could
+// not be obtained because STS refused to issue them.
+func isAssumeRoleFailure(err error) bool {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		opErr, ok := e.(*smithy.OperationError)
+		if !ok || opErr.Service() != "STS" || opErr.Operation() != "AssumeRole" {
+			continue
+		}
+		var apiErr smithy.APIError
+		if errors.As(opErr, &apiErr) && apiErr.ErrorCode() == "AccessDenied" {
+			return true
+		}
+	}
+	return false
+}
+
+// isAccessDenied reports whether AWS refused the request for lack of
+// permission, whether from an identity, a resource or a delegation policy.
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "AccessDenied", "AccessDeniedException":
+		return true
+	}
+	return false
+}
+
+func isThrottleCode(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "Throttling", "ThrottlingException", "ThrottledException", "RequestThrottled",
+		"RequestThrottledException", "TooManyRequestsException", "RequestLimitExceeded",
+		"SlowDown", "PriorRequestNotComplete":
+		return true
+	}
+	return false
+}
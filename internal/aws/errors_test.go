@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestNewErrorClassification(t *testing.T) {
+	msg := "not allowed"
+	cases := []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{"no such entity", &iamtypes.NoSuchEntityException{Message: &msg}, ErrNoSuchEntity},
+		{"already exists", &iamtypes.EntityAlreadyExistsException{Message: &msg}, ErrEntityAlreadyExists},
+		{"missing profile", config.SharedConfigProfileNotExistError{Profile: "nope"}, ErrMissingProfile},
+		{"throttled", &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}, ErrThrottled},
+		{"scp", &smithy.GenericAPIError{Code: "AccessDenied", Message: "with an explicit deny in a service control policy"}, ErrSCPDenied},
+		{"no such bucket", &smithy.GenericAPIError{Code: "NoSuchBucket"}, ErrBucketNotFound},
+		{"assume role denied", &smithy.OperationError{
+			ServiceID:     "IAM",
+			OperationName: "GetRole",
+			Err: fmt.Errorf("failed to refresh cached credentials, %w", &smithy.OperationError{
+				ServiceID:     "STS",
+				OperationName: "AssumeRole",
+				Err:           &smithy.GenericAPIError{Code: "AccessDenied", Message: msg},
+			}),
+		}, ErrAssumeRoleDenied},
+		{"unknown", errors.New("boom"), ErrUnknown},
+	}
+	for _, c := range cases {
+		err := newError(StepGetRole, c.err)
+		awsErr, ok := AsError(err)
+		if !ok {
+			t.Fatalf("%s: expected *Error, got %T", c.name, err)
+		}
+		if awsErr.Kind != c.kind {
+			t.Errorf("%s: expected kind %d, got %d", c.name, c.kind, awsErr.Kind)
+		}
+		if awsErr.Step != StepGetRole {
+			t.Errorf("%s: expected step %q, got %q", c.name, StepGetRole, awsErr.Step)
+		}
+		if err.Error() != c.err.Error() {
+			t.Errorf("%s: expected message %q, got %q", c.name, c.err.Error(), err.Error())
+		}
+	}
+}
+
+func TestNewErrorKeepsFirstStep(t *testing.T) {
+	err := newError(StepAttachPolicy, newError(StepGetRole, errors.New("boom")))
+	awsErr, _ := AsError(err)
+	if awsErr.Step != StepGetRole {
+		t.Errorf("expected step %q, got %q", StepGetRole, awsErr.Step)
+	}
+	if newError(StepGetRole, nil) != nil {
+		t.Error("expected nil for nil error")
+	}
+}
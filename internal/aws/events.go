@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgetypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// ForwardingPolicyName is the name of the inline policy letting the
+// forwarding role put events on the Uptycs event bus.
+const ForwardingPolicyName = "UptycsPutEventsPolicy"
+
+// forwardingTargetID identifies the Uptycs event bus among the targets of a
+// forwarding rule.
+const forwardingTargetID = "UptycsEventBus"
+
+// managementEventPattern matches the CloudTrail management events delivered
+// to the default event bus.
+const managementEventPattern = `{"detail-type":["AWS API Call via CloudTrail","AWS Console Sign In via CloudTrail"]}`
+
+// EventForwardingSpec describes the forwarding of the CloudTrail management
+// events of an account to the Uptycs event bus, by a rule named RuleName in
+// each of Regions and a role named RoleName.
+type EventForwardingSpec struct {
+	ProfileName          string
+	AccountID            string
+	RoleToAssume         string
+	UseCallerCredentials bool
+	Regions              []string
+	EventBusArn          string
+	RuleName             string
+	RoleName             string
+}
+
+// forwardingTrustPolicy lets EventBridge of the account assume the
+// forwarding role.
+func forwardingTrustPolicy(accountID string) *PolicyDocument {
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"Service": StringList{"events.amazonaws.com"}},
+				Action:    StringList{"sts:AssumeRole"},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {"aws:SourceAccount": StringList{accountID}},
+				},
+			},
+		},
+	}
+}
+
+// forwardingPolicy lets the forwarding role put events on the event bus.
+func forwardingPolicy(eventBusArn string) *PolicyDocument {
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Effect:   "Allow",
+				Action:   StringList{"events:PutEvents"},
+				Resource: StringList{eventBusArn},
+			},
+		},
+	}
+}
+
+func getEventBridgeClient(ctx context.Context, spec EventForwardingSpec, regionCode string) (*eventbridge.Client, error) {
+	sess, err := getAwsConfigForAccount(ctx, spec.ProfileName, regionCode, spec.AccountID, spec.RoleToAssume, spec.UseCallerCredentials)
+	if err != nil {
+		return nil, err
+	}
+	return eventbridge.NewFromConfig(*sess), nil
+}
+
+// PutForwardingRole creates the forwarding role of spec, or updates the
+// policies of an existing one, as when the resource is updated. It returns
+// the role ARN and whether the role was created.
+func PutForwardingRole(ctx context.Context, svc *iam.Client, spec EventForwardingSpec) (string, bool, error) {
+	trustPolicy := forwardingTrustPolicy(spec.AccountID).String()
+	roleArn, err := GetIntegrationRoleName(ctx, svc, spec.RoleName)
+	created := false
+	if IsKind(err, ErrNoSuchEntity) {
+		out, err := svc.CreateRole(ctx, &iam.CreateRoleInput{
+			RoleName:                 &spec.RoleName,
+			AssumeRolePolicyDocument: &trustPolicy,
+			Description:              aws.String("Lets EventBridge forward CloudTrail management events to Uptycs"),
+		})
+		if err != nil {
+			return "", false, newError(StepCreateRole, err)
+		}
+		if out.Role == nil || out.Role.Arn == nil {
+			return "", false, newError(StepCreateRole, fmt.Errorf("invalid CreateRoleOutput for %s", spec.RoleName))
+		}
+		roleArn = *out.Role.Arn
+		created = true
+	} else if err != nil {
+		return "", false, err
+	} else if _, err := svc.UpdateAssumeRolePolicy(ctx, &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       &spec.RoleName,
+		PolicyDocument: &trustPolicy,
+	}); err != nil {
+		return "", false, newError(StepCreateRole, err)
+	}
+	policy := forwardingPolicy(spec.EventBusArn).String()
+	if _, err := svc.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       &spec.RoleName,
+		PolicyName:     aws.String(ForwardingPolicyName),
+		PolicyDocument: &policy,
+	}); err != nil {
+		return roleArn, created, newError(StepPutInlinePolicy, err)
+	}
+	return roleArn, created, nil
+}
+
+// DeleteForwardingRole deletes the forwarding role and its policy. A role
+// that no longer exists is skipped.
+func DeleteForwardingRole(ctx context.Context, svc *iam.Client, roleName string) error {
+	_, err := svc.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{
+		RoleName:   &roleName,
+		PolicyName: aws.String(ForwardingPolicyName),
+	})
+	if err != nil && !IsKind(err, ErrNoSuchEntity) {
+		return newError(StepDeleteInlinePolicy, err)
+	}
+	_, err = svc.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: &roleName})
+	if err != nil && !IsKind(err, ErrNoSuchEntity) {
+		return newError(StepDeleteRole, err)
+	}
+	return nil
+}
+
+// PutForwardingRule creates or updates the forwarding rule of spec in the
+// region, targeting the event bus through roleArn, and returns the rule ARN.
+// The ARN is also returned when the target could not be put, since the rule
+// exists by then.
+func PutForwardingRule(ctx context.Context, spec EventForwardingSpec, regionCode string, roleArn string) (string, error) {
+	svc, err := getEventBridgeClient(ctx, spec, regionCode)
+	if err != nil {
+		return "", err
+	}
+	rule, err := svc.PutRule(ctx, &eventbridge.PutRuleInput{
+		Name:         &spec.RuleName,
+		Description:  aws.String("Forwards CloudTrail management events to Uptycs"),
+		EventPattern: aws.String(managementEventPattern),
+		State:        eventbridgetypes.RuleStateEnabled,
+	})
+	if err != nil {
+		return "", newError(StepPutRule, err)
+	}
+	if rule.RuleArn == nil {
+		return "", newError(StepPutRule, fmt.Errorf("invalid PutRuleOutput for %s in %s", spec.RuleName, regionCode))
+	}
+	targets, err := svc.PutTargets(ctx, &eventbridge.PutTargetsInput{
+		Rule: &spec.RuleName,
+		Targets: []eventbridgetypes.Target{
+			{
+				Id:      aws.String(forwardingTargetID),
+				Arn:     &spec.EventBusArn,
+				RoleArn: &roleArn,
+			},
+		},
+	})
+	if err != nil {
+		return *rule.RuleArn, newError(StepPutTargets, err)
+	}
+	if len(targets.FailedEntries) > 0 {
+		failed := targets.FailedEntries[0]
+		return *rule.RuleArn, newError(StepPutTargets, fmt.Errorf("%s: %s", aws.ToString(failed.ErrorCode), aws.ToString(failed.ErrorMessage)))
+	}
+	return *rule.RuleArn, nil
+}
+
+// ForwardingRuleExists reports whether the forwarding rule of spec exists in
+// the region.
+func ForwardingRuleExists(ctx context.Context, spec EventForwardingSpec, regionCode string) (bool, error) {
+	svc, err := getEventBridgeClient(ctx, spec, regionCode)
+	if err != nil {
+		return false, err
+	}
+	_, err = svc.DescribeRule(ctx, &eventbridge.DescribeRuleInput{Name: &spec.RuleName})
+	var notFound *eventbridgetypes.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, newError(StepGetRule, err)
+	}
+	return true, nil
+}
+
+// DeleteForwardingRule removes the target of the forwarding rule of spec in
+// the region, then the rule. A rule that no longer exists is skipped.
+func DeleteForwardingRule(ctx context.Context, spec EventForwardingSpec, regionCode string) error {
+	svc, err := getEventBridgeClient(ctx, spec, regionCode)
+	if err != nil {
+		return err
+	}
+	var notFound *eventbridgetypes.ResourceNotFoundException
+	_, err = svc.RemoveTargets(ctx, &eventbridge.RemoveTargetsInput{
+		Rule: &spec.RuleName,
+		Ids:  []string{forwardingTargetID},
+	})
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return newError(StepDeleteRule, err)
+	}
+	_, err = svc.DeleteRule(ctx, &eventbridge.DeleteRuleInput{Name: &spec.RuleName})
+	if err != nil && !errors.As(err, &notFound) {
+		return newError(StepDeleteRule, err)
+	}
+	return nil
+}
+
+// CreateEventForwarding creates the forwarding role and the forwarding rule
+// of every region of spec. It returns the role ARN and the rule ARNs in the
+// order of the regions. It fails when the role or one of the rules already
+// exists, so that resources created outside of Terraform are not taken over.
+// When a step fails, what was created before is removed again.
+func CreateEventForwarding(ctx context.Context, svc *iam.Client, spec EventForwardingSpec) (string, []string, error) {
+	var created journal
+	fail := func(step string, err error) (string, []string, error) {
+		awsErr := wrapError(step, err)
+		awsErr.CleanupErr = created.rollback(ctx)
+		return "", nil, awsErr
+	}
+
+	existingArn, err := GetIntegrationRoleName(ctx, svc, spec.RoleName)
+	if err == nil {
+		return "", nil, errAlreadyExists(StepCreateRole, "role %s already exists", existingArn)
+	}
+	if !IsKind(err, ErrNoSuchEntity) {
+		return "", nil, err
+	}
+	for _, region := range spec.Regions {
+		exists, err := ForwardingRuleExists(ctx, spec, region)
+		if err != nil {
+			return "", nil, err
+		}
+		if exists {
+			return "", nil, errAlreadyExists(StepPutRule, "rule %s already exists in %s", spec.RuleName, region)
+		}
+	}
+
+	roleArn, roleCreated, err := PutForwardingRole(ctx, svc, spec)
+	if roleCreated {
+		created.record(StepCreateRole, func(ctx context.Context) error {
+			return DeleteForwardingRole(ctx, svc, spec.RoleName)
+		})
+	}
+	if err != nil {
+		return fail(StepCreateRole, err)
+	}
+
+	var ruleArns []string
+	for _, region := range spec.Regions {
+		ruleArn, err := PutForwardingRule(ctx, spec, region, roleArn)
+		if ruleArn != "" {
+			region := region
+			created.record(StepPutRule, func(ctx context.Context) error {
+				return DeleteForwardingRule(ctx, spec, region)
+			})
+		}
+		if err != nil {
+			return fail(StepPutRule, err)
+		}
+		ruleArns = append(ruleArns, ruleArn)
+	}
+	return roleArn, ruleArns, nil
+}
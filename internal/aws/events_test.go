@@ -0,0 +1,36 @@
+package aws
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestForwardingPolicies(t *testing.T) {
+	trust, err := ParsePolicyDocument(forwardingTrustPolicy("123456789012").String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if services := trust.Statement[0].Principal["Service"]; !reflect.DeepEqual(services, StringList{"events.amazonaws.com"}) {
+		t.Errorf("unexpected trusted services %v", services)
+	}
+	if accounts := trust.conditionValues("aws:SourceAccount"); !reflect.DeepEqual(accounts, []string{"123456789012"}) {
+		t.Errorf("unexpected source accounts %v", accounts)
+	}
+
+	busArn := "arn:aws:events:us-east-1:012345678912:event-bus/uptycs"
+	policy := forwardingPolicy(busArn)
+	if !reflect.DeepEqual(policy.Statement[0].Resource, StringList{busArn}) {
+		t.Errorf("unexpected resources %v", policy.Statement[0].Resource)
+	}
+}
+
+func TestManagementEventPattern(t *testing.T) {
+	var pattern map[string][]string
+	if err := json.Unmarshal([]byte(managementEventPattern), &pattern); err != nil {
+		t.Fatal(err)
+	}
+	if len(pattern["detail-type"]) == 0 {
+		t.Errorf("unexpected pattern %v", pattern)
+	}
+}
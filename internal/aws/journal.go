@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// cleanupTimeout bounds the rollback of a failed operation. Rollback runs on
+// a context detached from the operation's own deadline so that resources
+// created before a timeout are still removed.
+const cleanupTimeout = 2 * time.Minute
+
+// journal records the resources created during an operation so that exactly
+// those resources can be removed, in reverse order, when a later step fails.
+type journal struct {
+	entries []journalEntry
+}
+
+type journalEntry struct {
+	step string
+	undo func(ctx context.Context) error
+}
+
+func (j *journal) record(step string, undo func(ctx context.Context) error) {
+	j.entries = append(j.entries, journalEntry{step: step, undo: undo})
+}
+
+// rollback undoes every recorded entry, newest first. It keeps going after a
+// failed entry and returns all failures together.
+func (j *journal) rollback(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+	defer cancel()
+
+	var failures []string
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entry := j.entries[i]
+		if err := entry.undo(ctx); err != nil {
+			failures = append(failures, fmt.Sprintf("undo %s: %s", entry.step, err))
+		}
+	}
+	j.entries = nil
+	if len(failures) > 0 {
+		return fmt.Errorf("cleanup incomplete: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJournalRollback(t *testing.T) {
+	var undone []string
+	undo := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if ctx.Err() != nil {
+				t.Errorf("undo %s: rollback context is done: %v", name, ctx.Err())
+			}
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("undo %s: rollback context has no deadline", name)
+			}
+			undone = append(undone, name)
+			return err
+		}
+	}
+
+	// As recorded by CreateEventForwarding when the target of the rule of
+	// the second region could not be put.
+	var created journal
+	created.record(StepCreateRole, undo("role", nil))
+	created.record(StepPutRule, undo("rule us-east-1", errors.New("access denied")))
+	created.record(StepPutRule, undo("rule eu-west-1", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := created.rollback(ctx)
+
+	if expected := []string{"rule eu-west-1", "rule us-east-1", "role"}; !reflect.DeepEqual(undone, expected) {
+		t.Errorf("expected %v to be undone, got %v", expected, undone)
+	}
+	if err == nil || !strings.Contains(err.Error(), "undo "+StepPutRule+": access denied") {
+		t.Errorf("expected the failed undo to be reported, got %v", err)
+	}
+
+	undone = nil
+	if err := created.rollback(context.Background()); err != nil || len(undone) != 0 {
+		t.Errorf("expected nothing left to undo, got %v and %v", undone, err)
+	}
+}
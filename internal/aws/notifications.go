@@ -0,0 +1,411 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	storage "github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// LogQueueActions are the permissions the integration role needs to consume
+// the log notifications queue.
+var LogQueueActions = []string{
+	"sqs:ChangeMessageVisibility",
+	"sqs:DeleteMessage",
+	"sqs:GetQueueAttributes",
+	"sqs:ReceiveMessage",
+}
+
+// LogNotificationSpec describes the notifications of new logs of a CloudTrail
+// bucket, published to an SNS topic and delivered to an SQS queue, both
+// named Name, in the account and region of the bucket.
+type LogNotificationSpec struct {
+	Name         string
+	AccountID    string
+	BucketName   string
+	BucketRegion string
+	Prefix       string
+
+	// RoleArn is the integration role allowed to consume the queue. It may
+	// be empty.
+	RoleArn string
+}
+
+// NotificationID identifies the topic configuration of the spec in the
+// notification configuration of the bucket.
+func (s LogNotificationSpec) NotificationID() string {
+	return "uptycscspm-" + s.Name
+}
+
+// TopicArn returns the ARN of the topic of the spec.
+func (s LogNotificationSpec) TopicArn() string {
+	return fmt.Sprintf("arn:aws:sns:%s:%s:%s", s.BucketRegion, s.AccountID, s.Name)
+}
+
+// QueueArn returns the ARN of the queue of the spec.
+func (s LogNotificationSpec) QueueArn() string {
+	return fmt.Sprintf("arn:aws:sqs:%s:%s:%s", s.BucketRegion, s.AccountID, s.Name)
+}
+
+// LogNotification is the topic and queue created by CreateLogNotification.
+type LogNotification struct {
+	TopicArn string
+	QueueArn string
+	QueueURL string
+}
+
+// LogNotificationClients are the clients of the bucket owner account used
+// to manage log notifications.
+type LogNotificationClients struct {
+	S3  *storage.Client
+	SNS *sns.Client
+	SQS *sqs.Client
+}
+
+// GetLogNotificationClients returns the clients of childAccountID for the
+// region of the bucket. The S3 client is the one of GetAwsS3Client, the
+// others share its configuration so that the organization access role is
+// only assumed once.
+func GetLogNotificationClients(ctx context.Context, profileName string, regionCode string, childAccountID string, roleToAssume string, useCallerCredentials bool) (*LogNotificationClients, error) {
+	sess, err := getAwsConfigForAccount(ctx, profileName, regionCode, childAccountID, roleToAssume, useCallerCredentials)
+	if err != nil {
+		return nil, err
+	}
+	s3Client, err := newS3Client(sess, profileName, regionCode, childAccountID)
+	if err != nil {
+		return nil, err
+	}
+	return &LogNotificationClients{
+		S3:  s3Client,
+		SNS: sns.NewFromConfig(*sess),
+		SQS: sqs.NewFromConfig(*sess),
+	}, nil
+}
+
+// topicExists reports whether the topic topicArn exists.
+func topicExists(ctx context.Context, svc *sns.Client, topicArn string) (bool, error) {
+	if _, err := svc.GetTopicAttributes(ctx, &sns.GetTopicAttributesInput{TopicArn: &topicArn}); err != nil {
+		var notFound *snstypes.NotFoundException
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, newError(StepGetTopic, err)
+	}
+	return true, nil
+}
+
+// queueExists reports whether the queue named name exists.
+func queueExists(ctx context.Context, svc *sqs.Client, name string) (bool, error) {
+	if _, err := svc.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: &name}); err != nil {
+		var notFound *sqstypes.QueueDoesNotExist
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, newError(StepGetQueue, err)
+	}
+	return true, nil
+}
+
+// logTopicPolicy lets the bucket publish to the topic.
+func logTopicPolicy(spec LogNotificationSpec) *PolicyDocument {
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Sid:       "AllowBucketPublish",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"Service": StringList{"s3.amazonaws.com"}},
+				Action:    StringList{"sns:Publish"},
+				Resource:  StringList{spec.TopicArn()},
+				Condition: map[string]map[string]StringList{
+					"ArnLike":      {"aws:SourceArn": StringList{"arn:aws:s3:::" + spec.BucketName}},
+					"StringEquals": {"aws:SourceAccount": StringList{spec.AccountID}},
+				},
+			},
+		},
+	}
+}
+
+// logQueuePolicy lets the topic send to the queue and the integration role
+// consume it.
+func logQueuePolicy(spec LogNotificationSpec) *PolicyDocument {
+	policy := &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Sid:       "AllowTopicSend",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"Service": StringList{"sns.amazonaws.com"}},
+				Action:    StringList{"sqs:SendMessage"},
+				Resource:  StringList{spec.QueueArn()},
+				Condition: map[string]map[string]StringList{
+					"ArnEquals": {"aws:SourceArn": StringList{spec.TopicArn()}},
+				},
+			},
+		},
+	}
+	if spec.RoleArn != "" {
+		policy.Statement = append(policy.Statement, PolicyStatement{
+			Sid:       "AllowUptycsConsume",
+			Effect:    "Allow",
+			Principal: PolicyPrincipal{"AWS": StringList{spec.RoleArn}},
+			Action:    LogQueueActions,
+			Resource:  StringList{spec.QueueArn()},
+		})
+	}
+	return policy
+}
+
+// logTopicConfiguration returns the topic configuration publishing the new
+// logs of the bucket.
+func logTopicConfiguration(spec LogNotificationSpec) s3types.TopicConfiguration {
+	configuration := s3types.TopicConfiguration{
+		Id:       aws.String(spec.NotificationID()),
+		TopicArn: aws.String(spec.TopicArn()),
+		Events:   []s3types.Event{"s3:ObjectCreated:*"},
+	}
+	if spec.Prefix != "" {
+		configuration.Filter = &s3types.NotificationConfigurationFilter{
+			Key: &s3types.S3KeyFilter{
+				FilterRules: []s3types.FilterRule{{Name: s3types.FilterRuleNamePrefix, Value: aws.String(spec.Prefix)}},
+			},
+		}
+	}
+	return configuration
+}
+
+// putTopicConfiguration replaces the topic configuration with the same ID,
+// or appends it. The other configurations are kept as they are.
+func putTopicConfiguration(notifications *s3types.NotificationConfiguration, configuration s3types.TopicConfiguration) {
+	for i, existing := range notifications.TopicConfigurations {
+		if aws.ToString(existing.Id) == aws.ToString(configuration.Id) {
+			notifications.TopicConfigurations[i] = configuration
+			return
+		}
+	}
+	notifications.TopicConfigurations = append(notifications.TopicConfigurations, configuration)
+}
+
+// removeTopicConfiguration removes the topic configuration with the ID and
+// reports whether it was found.
+func removeTopicConfiguration(notifications *s3types.NotificationConfiguration, id string) bool {
+	for i, existing := range notifications.TopicConfigurations {
+		if aws.ToString(existing.Id) == id {
+			notifications.TopicConfigurations = append(notifications.TopicConfigurations[:i], notifications.TopicConfigurations[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func getBucketNotifications(ctx context.Context, svc *storage.Client, bucketName string) (*s3types.NotificationConfiguration, error) {
+	out, err := svc.GetBucketNotificationConfiguration(ctx, &storage.GetBucketNotificationConfigurationInput{Bucket: &bucketName})
+	if err != nil {
+		return nil, newError(StepGetNotifications, err)
+	}
+	return &s3types.NotificationConfiguration{
+		EventBridgeConfiguration:     out.EventBridgeConfiguration,
+		LambdaFunctionConfigurations: out.LambdaFunctionConfigurations,
+		QueueConfigurations:          out.QueueConfigurations,
+		TopicConfigurations:          out.TopicConfigurations,
+	}, nil
+}
+
+// updateBucketNotifications applies update to the notification configuration
+// of the bucket and writes it back when update reports a change.
+func updateBucketNotifications(ctx context.Context, svc *storage.Client, bucketName string, update func(*s3types.NotificationConfiguration) bool) error {
+	unlock := lockBucket(bucketName, "notifications")
+	defer unlock()
+
+	notifications, err := getBucketNotifications(ctx, svc, bucketName)
+	if err != nil {
+		return err
+	}
+	if !update(notifications) {
+		return nil
+	}
+	_, err = svc.PutBucketNotificationConfiguration(ctx, &storage.PutBucketNotificationConfigurationInput{
+		Bucket:                    &bucketName,
+		NotificationConfiguration: notifications,
+	})
+	return newError(StepPutNotifications, err)
+}
+
+// CreateLogNotification creates the topic and the queue of spec, subscribes
+// the queue to the topic and adds the topic to the notification
+// configuration of the bucket. It fails when the topic or the queue already
+// exists, since creating them again would silently take them over. When a
+// step fails, what was created before is removed again.
+func CreateLogNotification(ctx context.Context, clients *LogNotificationClients, spec LogNotificationSpec) (*LogNotification, error) {
+	var created journal
+	fail := func(step string, err error) (*LogNotification, error) {
+		awsErr := wrapError(step, err)
+		awsErr.CleanupErr = created.rollback(ctx)
+		return nil, awsErr
+	}
+
+	topicFound, err := topicExists(ctx, clients.SNS, spec.TopicArn())
+	if err != nil {
+		return nil, err
+	}
+	if topicFound {
+		return nil, errAlreadyExists(StepCreateTopic, "topic %s already exists", spec.TopicArn())
+	}
+	queueFound, err := queueExists(ctx, clients.SQS, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	if queueFound {
+		return nil, errAlreadyExists(StepCreateQueue, "queue %s already exists", spec.QueueArn())
+	}
+
+	topicPolicy := logTopicPolicy(spec).String()
+	topic, err := clients.SNS.CreateTopic(ctx, &sns.CreateTopicInput{
+		Name:       &spec.Name,
+		Attributes: map[string]string{"Policy": topicPolicy},
+	})
+	if err != nil {
+		return fail(StepCreateTopic, err)
+	}
+	if topic.TopicArn == nil {
+		return fail(StepCreateTopic, fmt.Errorf("invalid CreateTopicOutput for %s", spec.Name))
+	}
+	notification := &LogNotification{TopicArn: *topic.TopicArn}
+	created.record(StepCreateTopic, func(ctx context.Context) error {
+		return deleteTopic(ctx, clients.SNS, notification.TopicArn)
+	})
+
+	queuePolicy := logQueuePolicy(spec).String()
+	queue, err := clients.SQS.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  &spec.Name,
+		Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): queuePolicy},
+	})
+	if err != nil {
+		return fail(StepCreateQueue, err)
+	}
+	if queue.QueueUrl == nil {
+		return fail(StepCreateQueue, fmt.Errorf("invalid CreateQueueOutput for %s", spec.Name))
+	}
+	notification.QueueURL = *queue.QueueUrl
+	notification.QueueArn = spec.QueueArn()
+	created.record(StepCreateQueue, func(ctx context.Context) error {
+		return deleteQueue(ctx, clients.SQS, notification.QueueURL)
+	})
+
+	// Raw delivery passes the S3 event through as the message body.
+	if _, err := clients.SNS.Subscribe(ctx, &sns.SubscribeInput{
+		TopicArn:   &notification.TopicArn,
+		Protocol:   aws.String("sqs"),
+		Endpoint:   &notification.QueueArn,
+		Attributes: map[string]string{"RawMessageDelivery": "true"},
+	}); err != nil {
+		return fail(StepSubscribeQueue, err)
+	}
+
+	if err := updateBucketNotifications(ctx, clients.S3, spec.BucketName, func(notifications *s3types.NotificationConfiguration) bool {
+		putTopicConfiguration(notifications, logTopicConfiguration(spec))
+		return true
+	}); err != nil {
+		return fail(StepPutNotifications, err)
+	}
+	return notification, nil
+}
+
+// UpdateLogQueueConsumer updates the queue policy of spec for a new RoleArn.
+func UpdateLogQueueConsumer(ctx context.Context, clients *LogNotificationClients, spec LogNotificationSpec, queueURL string) error {
+	policy := logQueuePolicy(spec).String()
+	_, err := clients.SQS.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   &queueURL,
+		Attributes: map[string]string{string(sqstypes.QueueAttributeNamePolicy): policy},
+	})
+	return newError(StepSetQueuePolicy, err)
+}
+
+// LogNotificationStatus tells which parts of the log notifications of a
+// spec exist.
+type LogNotificationStatus struct {
+	Topic              bool
+	Queue              bool
+	TopicConfiguration bool
+}
+
+// GetLogNotificationStatus looks up the topic, the queue and the topic
+// configuration of the bucket of spec. A missing bucket has no topic
+// configuration.
+func GetLogNotificationStatus(ctx context.Context, clients *LogNotificationClients, spec LogNotificationSpec) (LogNotificationStatus, error) {
+	var status LogNotificationStatus
+	var err error
+	if status.Topic, err = topicExists(ctx, clients.SNS, spec.TopicArn()); err != nil {
+		return status, err
+	}
+	if status.Queue, err = queueExists(ctx, clients.SQS, spec.Name); err != nil {
+		return status, err
+	}
+	notifications, err := getBucketNotifications(ctx, clients.S3, spec.BucketName)
+	if IsKind(err, ErrBucketNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	for _, configuration := range notifications.TopicConfigurations {
+		if aws.ToString(configuration.Id) == spec.NotificationID() {
+			status.TopicConfiguration = true
+		}
+	}
+	return status, nil
+}
+
+// PutLogTopicConfiguration adds the topic configuration of spec to the
+// notification configuration of the bucket again, after it was removed
+// outside of Terraform.
+func PutLogTopicConfiguration(ctx context.Context, clients *LogNotificationClients, spec LogNotificationSpec) error {
+	return updateBucketNotifications(ctx, clients.S3, spec.BucketName, func(notifications *s3types.NotificationConfiguration) bool {
+		putTopicConfiguration(notifications, logTopicConfiguration(spec))
+		return true
+	})
+}
+
+// DeleteLogNotification removes the topic configuration from the bucket and
+// deletes the queue and the topic, along with its subscription. Resources
+// that no longer exist are skipped.
+func DeleteLogNotification(ctx context.Context, clients *LogNotificationClients, spec LogNotificationSpec, queueURL string) error {
+	err := updateBucketNotifications(ctx, clients.S3, spec.BucketName, func(notifications *s3types.NotificationConfiguration) bool {
+		return removeTopicConfiguration(notifications, spec.NotificationID())
+	})
+	if err != nil && !IsKind(err, ErrBucketNotFound) {
+		return err
+	}
+	if err := deleteQueue(ctx, clients.SQS, queueURL); err != nil {
+		return newError(StepDeleteQueue, err)
+	}
+	if err := deleteTopic(ctx, clients.SNS, spec.TopicArn()); err != nil {
+		return newError(StepDeleteTopic, err)
+	}
+	return nil
+}
+
+func deleteTopic(ctx context.Context, svc *sns.Client, topicArn string) error {
+	_, err := svc.DeleteTopic(ctx, &sns.DeleteTopicInput{TopicArn: &topicArn})
+	var notFound *snstypes.NotFoundException
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
+func deleteQueue(ctx context.Context, svc *sqs.Client, queueURL string) error {
+	_, err := svc.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: &queueURL})
+	var notFound *sqstypes.QueueDoesNotExist
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestTopicConfigurationMerge(t *testing.T) {
+	notifications := &s3types.NotificationConfiguration{
+		TopicConfigurations: []s3types.TopicConfiguration{
+			{Id: aws.String("audit"), TopicArn: aws.String("arn:aws:sns:us-east-1:234567890123:audit")},
+		},
+		QueueConfigurations: []s3types.QueueConfiguration{
+			{Id: aws.String("archive"), QueueArn: aws.String("arn:aws:sqs:us-east-1:234567890123:archive")},
+		},
+	}
+	spec := LogNotificationSpec{
+		Name:         "uptycs-cloudtrail",
+		AccountID:    "234567890123",
+		BucketName:   "logs",
+		BucketRegion: "us-east-1",
+	}
+
+	putTopicConfiguration(notifications, logTopicConfiguration(spec))
+	spec.Prefix = "AWSLogs/o-abcdef1234/"
+	putTopicConfiguration(notifications, logTopicConfiguration(spec))
+	if len(notifications.TopicConfigurations) != 2 || len(notifications.QueueConfigurations) != 1 {
+		t.Fatalf("unexpected configurations %+v", notifications)
+	}
+	configuration := notifications.TopicConfigurations[1]
+	if aws.ToString(configuration.TopicArn) != "arn:aws:sns:us-east-1:234567890123:uptycs-cloudtrail" {
+		t.Errorf("unexpected topic %s", aws.ToString(configuration.TopicArn))
+	}
+	if configuration.Filter == nil || aws.ToString(configuration.Filter.Key.FilterRules[0].Value) != spec.Prefix {
+		t.Errorf("unexpected filter %+v", configuration.Filter)
+	}
+
+	if !removeTopicConfiguration(notifications, spec.NotificationID()) || removeTopicConfiguration(notifications, spec.NotificationID()) {
+		t.Error("expected the configuration to be removed exactly once")
+	}
+	if len(notifications.TopicConfigurations) != 1 || aws.ToString(notifications.TopicConfigurations[0].Id) != "audit" {
+		t.Errorf("unexpected configurations after removal %+v", notifications.TopicConfigurations)
+	}
+}
+
+func TestLogQueuePolicy(t *testing.T) {
+	spec := LogNotificationSpec{
+		Name:         "uptycs-cloudtrail",
+		AccountID:    "234567890123",
+		BucketName:   "logs",
+		BucketRegion: "us-east-1",
+	}
+	if policy := logQueuePolicy(spec); len(policy.Statement) != 1 {
+		t.Errorf("expected only the topic statement without a role, got %d", len(policy.Statement))
+	}
+	spec.RoleArn = "arn:aws:iam::123456789012:role/UptycsIntegration"
+	policy, err := ParsePolicyDocument(logQueuePolicy(spec).String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principals := policy.principals(); len(principals) != 1 || principals[0] != spec.RoleArn {
+		t.Errorf("unexpected principals %v", principals)
+	}
+}
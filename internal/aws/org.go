@@ -0,0 +1,457 @@
+package aws
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	org "github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"golang.org/x/sync/singleflight"
+)
+
+const AccountStatusActive = "ACTIVE"
+
+// Account is a member account of an AWS organization.
+type Account struct {
+	ID              string
+	Arn             string
+	Name            string
+	Email           string
+	Status          string
+	JoinedMethod    string
+	JoinedTimestamp time.Time
+	// ParentID is the root or organizational unit directly containing the
+	// account. It is only set on accounts returned by FilterAccounts, when
+	// the filter has ParentIDs or ResolveParents.
+	ParentID string
+}
+
+// AccountFilter selects organization accounts. Empty fields do not filter.
+type AccountFilter struct {
+	// ParentIDs are roots or organizational units. An account matches when
+	// it is contained in one of them, directly or through nested units.
+	ParentIDs  []string
+	Statuses   []string
+	Tags       map[string]string
+	NameRegex  *regexp.Regexp
+	ExcludeIDs []string
+	// ResolveParents sets the ParentID of the returned accounts, which
+	// walks the organization tree even without ParentIDs.
+	ResolveParents bool
+}
+
+// orgAPI is the subset of the Organizations client used by OrgInventory.
+type orgAPI interface {
+	org.ListAccountsAPIClient
+	org.ListAccountsForParentAPIClient
+	org.ListRootsAPIClient
+	org.ListChildrenAPIClient
+	org.ListTagsForResourceAPIClient
+}
+
+// OrgInventory lists the accounts of AWS organizations. Every listing is
+// fetched once and cached for the lifetime of the provider, which is a single
+// Terraform run, and concurrent callers asking for the same listing share one
+// in-flight request.
+//
+// The listing works from the management account as well as from a delegated
+// administrator account, whose delegation policy may not allow ListAccounts.
+type OrgInventory struct {
+	newClient func(ctx context.Context, profileName string) (orgAPI, error)
+	group     singleflight.Group
+
+	// roleArn is assumed with the profile's credentials before calling
+	// Organizations, when set.
+	roleArn string
+
+	mu    sync.Mutex
+	cache map[string]interface{}
+}
+
+func NewOrgInventory() *OrgInventory {
+	inv := &OrgInventory{
+		cache: make(map[string]interface{}),
+	}
+	inv.newClient = func(ctx context.Context, profileName string) (orgAPI, error) {
+		return GetOrgClient(ctx, profileName, inv.roleArn)
+	}
+	return inv
+}
+
+// SetRoleArn makes the inventory assume roleArn before calling
+// Organizations. It must be called before the first listing.
+func (inv *OrgInventory) SetRoleArn(roleArn string) {
+	inv.roleArn = roleArn
+}
+
+// loadTimeout bounds a listing shared by concurrent callers.
+const loadTimeout = 5 * time.Minute
+
+// load returns the cached value for key, calling fetch at most once at a
+// time to fill the cache. Errors are not cached.
+//
+// The callers waiting for the same key share one call of fetch, which runs on
+// a context detached from the caller that started it, so that its
+// cancellation does not fail the other callers. Each caller stops waiting
+// when its own context is done.
+func (inv *OrgInventory) load(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	inv.mu.Lock()
+	value, found := inv.cache[key]
+	inv.mu.Unlock()
+	if found {
+		return value, nil
+	}
+
+	results := inv.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		value, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		inv.mu.Lock()
+		inv.cache[key] = value
+		inv.mu.Unlock()
+		return value, nil
+	})
+	select {
+	case result := <-results:
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (inv *OrgInventory) client(ctx context.Context, profileName string) (orgAPI, error) {
+	svc, err := inv.load(ctx, "client/"+profileName, func(ctx context.Context) (interface{}, error) {
+		return inv.newClient(ctx, profileName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return svc.(orgAPI), nil
+}
+
+// Accounts returns every account of the organization that profileName
+// belongs to. The returned slice is shared and must not be modified.
+func (inv *OrgInventory) Accounts(ctx context.Context, profileName string) ([]Account, error) {
+	accounts, err := inv.load(ctx, "accounts/"+profileName, func(ctx context.Context) (interface{}, error) {
+		svc, err := inv.client(ctx, profileName)
+		if err != nil {
+			return nil, err
+		}
+		accounts, err := listOrgAccounts(ctx, svc)
+		if isAccessDenied(err) {
+			// ListAccounts is commonly left out of the delegation policy
+			// of delegated administrators, walk the tree instead.
+			return listOrgAccountsByParent(ctx, svc)
+		}
+		return accounts, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accounts.([]Account), nil
+}
+
+// Account returns the organization account accountID, or nil when it is not
+// a member of the organization.
+func (inv *OrgInventory) Account(ctx context.Context, profileName string, accountID string) (*Account, error) {
+	accounts, err := inv.Accounts(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		if accounts[i].ID == accountID {
+			account := accounts[i]
+			return &account, nil
+		}
+	}
+	return nil, nil
+}
+
+// Parents returns the parent root or organizational unit of every account
+// and organizational unit in the organization, keyed by child ID.
+func (inv *OrgInventory) Parents(ctx context.Context, profileName string) (map[string]string, error) {
+	parents, err := inv.load(ctx, "parents/"+profileName, func(ctx context.Context) (interface{}, error) {
+		svc, err := inv.client(ctx, profileName)
+		if err != nil {
+			return nil, err
+		}
+		return listOrgParents(ctx, svc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parents.(map[string]string), nil
+}
+
+// AccountTags returns the tags of an organization account.
+func (inv *OrgInventory) AccountTags(ctx context.Context, profileName string, accountID string) (map[string]string, error) {
+	tags, err := inv.load(ctx, "tags/"+profileName+"/"+accountID, func(ctx context.Context) (interface{}, error) {
+		svc, err := inv.client(ctx, profileName)
+		if err != nil {
+			return nil, err
+		}
+		return listOrgTags(ctx, svc, accountID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags.(map[string]string), nil
+}
+
+// FilterAccounts returns the organization accounts matching filter. Their
+// ParentID is set when the filter has ParentIDs or ResolveParents.
+func (inv *OrgInventory) FilterAccounts(ctx context.Context, profileName string, filter AccountFilter) ([]Account, error) {
+	accounts, err := inv.Accounts(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+	// walking the organization tree takes one request per unit, it is only
+	// done when the filter or the caller needs it
+	var parents map[string]string
+	if len(filter.ParentIDs) > 0 || filter.ResolveParents {
+		parents, err = inv.Parents(ctx, profileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := toSet(filter.Statuses)
+	excluded := toSet(filter.ExcludeIDs)
+	ancestors := toSet(filter.ParentIDs)
+
+	var matched []Account
+	for _, account := range accounts {
+		if excluded[account.ID] {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[account.Status] {
+			continue
+		}
+		if filter.NameRegex != nil && !filter.NameRegex.MatchString(account.Name) {
+			continue
+		}
+		if len(ancestors) > 0 && !hasAncestor(parents, account.ID, ancestors) {
+			continue
+		}
+		account.ParentID = parents[account.ID]
+		matched = append(matched, account)
+	}
+	if len(filter.Tags) == 0 {
+		return matched, nil
+	}
+	return inv.filterTags(ctx, profileName, matched, filter.Tags)
+}
+
+// tagConcurrency is the number of accounts whose tags are listed at once.
+// The Organizations API is throttled per organization, so it is kept low.
+const tagConcurrency = 5
+
+// filterTags returns the accounts having the wanted tags. The tags are
+// listed with one request per account, so only the accounts left by the
+// other filters are looked at.
+func (inv *OrgInventory) filterTags(ctx context.Context, profileName string, accounts []Account, wanted map[string]string) ([]Account, error) {
+	keep := make([]bool, len(accounts))
+	errs := make([]error, len(accounts))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < tagConcurrency && i < len(accounts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				tags, err := inv.AccountTags(ctx, profileName, accounts[index].ID)
+				errs[index] = err
+				keep[index] = err == nil && hasTags(tags, wanted)
+			}
+		}()
+	}
+	for i := range accounts {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var matched []Account
+	for i, account := range accounts {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if keep[i] {
+			matched = append(matched, account)
+		}
+	}
+	return matched, nil
+}
+
+func hasAncestor(parents map[string]string, id string, ancestors map[string]bool) bool {
+	// the depth of an organization is limited to five levels of units below
+	// the root, the bound only protects against malformed input
+	for i := 0; i < 10; i++ {
+		parent, found := parents[id]
+		if !found {
+			return false
+		}
+		if ancestors[parent] {
+			return true
+		}
+		id = parent
+	}
+	return false
+}
+
+func hasTags(tags map[string]string, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if actual, found := tags[key]; !found || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func listOrgAccounts(ctx context.Context, svc org.ListAccountsAPIClient) ([]Account, error) {
+	var accounts []Account
+	paginator := org.NewListAccountsPaginator(svc, &org.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, newError(StepListOrgAccounts, err)
+		}
+		for _, account := range page.Accounts {
+			accounts = append(accounts, newAccount(account))
+		}
+	}
+	return accounts, nil
+}
+
+func newAccount(account orgtypes.Account) Account {
+	return Account{
+		ID:              aws.ToString(account.Id),
+		Arn:             aws.ToString(account.Arn),
+		Name:            aws.ToString(account.Name),
+		Email:           aws.ToString(account.Email),
+		Status:          string(account.Status),
+		JoinedMethod:    string(account.JoinedMethod),
+		JoinedTimestamp: aws.ToTime(account.JoinedTimestamp),
+	}
+}
+
+// listOrgAccountsByParent lists the accounts of the organization by walking
+// its tree from the roots, with ListAccountsForParent and ListChildren.
+func listOrgAccountsByParent(ctx context.Context, svc orgAPI) ([]Account, error) {
+	var pending []string
+	roots := org.NewListRootsPaginator(svc, &org.ListRootsInput{})
+	for roots.HasMorePages() {
+		page, err := roots.NextPage(ctx)
+		if err != nil {
+			return nil, newError(StepListOrgTree, err)
+		}
+		for _, root := range page.Roots {
+			pending = append(pending, aws.ToString(root.Id))
+		}
+	}
+
+	var accounts []Account
+	for len(pending) > 0 {
+		parentID := pending[0]
+		pending = pending[1:]
+		paginator := org.NewListAccountsForParentPaginator(svc, &org.ListAccountsForParentInput{
+			ParentId: aws.String(parentID),
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, newError(StepListOrgAccounts, err)
+			}
+			for _, account := range page.Accounts {
+				accounts = append(accounts, newAccount(account))
+			}
+		}
+		children := org.NewListChildrenPaginator(svc, &org.ListChildrenInput{
+			ParentId:  aws.String(parentID),
+			ChildType: orgtypes.ChildTypeOrganizationalUnit,
+		})
+		for children.HasMorePages() {
+			page, err := children.NextPage(ctx)
+			if err != nil {
+				return nil, newError(StepListOrgTree, err)
+			}
+			for _, child := range page.Children {
+				pending = append(pending, aws.ToString(child.Id))
+			}
+		}
+	}
+	return accounts, nil
+}
+
+// listOrgParents walks the organization tree from its roots.
+func listOrgParents(ctx context.Context, svc orgAPI) (map[string]string, error) {
+	var pending []string
+	roots := org.NewListRootsPaginator(svc, &org.ListRootsInput{})
+	for roots.HasMorePages() {
+		page, err := roots.NextPage(ctx)
+		if err != nil {
+			return nil, newError(StepListOrgTree, err)
+		}
+		for _, root := range page.Roots {
+			pending = append(pending, aws.ToString(root.Id))
+		}
+	}
+
+	parents := make(map[string]string)
+	for len(pending) > 0 {
+		parentID := pending[0]
+		pending = pending[1:]
+		for _, childType := range []orgtypes.ChildType{orgtypes.ChildTypeAccount, orgtypes.ChildTypeOrganizationalUnit} {
+			children := org.NewListChildrenPaginator(svc, &org.ListChildrenInput{
+				ParentId:  aws.String(parentID),
+				ChildType: childType,
+			})
+			for children.HasMorePages() {
+				page, err := children.NextPage(ctx)
+				if err != nil {
+					return nil, newError(StepListOrgTree, err)
+				}
+				for _, child := range page.Children {
+					childID := aws.ToString(child.Id)
+					parents[childID] = parentID
+					if childType == orgtypes.ChildTypeOrganizationalUnit {
+						pending = append(pending, childID)
+					}
+				}
+			}
+		}
+	}
+	return parents, nil
+}
+
+func listOrgTags(ctx context.Context, svc org.ListTagsForResourceAPIClient, resourceID string) (map[string]string, error) {
+	tags := make(map[string]string)
+	paginator := org.NewListTagsForResourcePaginator(svc, &org.ListTagsForResourceInput{
+		ResourceId: aws.String(resourceID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, newError(StepListOrgTags, err)
+		}
+		for _, tag := range page.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+	return tags, nil
+}
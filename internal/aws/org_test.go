@@ -0,0 +1,318 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	org "github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeOrg is an in-memory organization with one account per page of
+// ListAccounts:
+//
+//	r-root
+//	├── 111111111111 (prod)
+//	└── ou-workloads
+//	    ├── 222222222222 (prod)
+//	    └── ou-sandbox
+//	        └── 333333333333 (sandbox, SUSPENDED)
+type fakeOrg struct {
+	listAccountsCalls int32
+	listChildrenCalls int32
+	listTagsCalls     int32
+	failListAccounts  int32
+	// denyListAccounts denies ListAccounts like the delegation policy of a
+	// delegated administrator may.
+	denyListAccounts bool
+	// release, when set, holds ListAccounts until it is closed.
+	release chan struct{}
+}
+
+var fakeAccounts = []orgtypes.Account{
+	{Id: aws.String("111111111111"), Name: aws.String("prod-core"), Status: orgtypes.AccountStatusActive},
+	{Id: aws.String("222222222222"), Name: aws.String("prod-app"), Status: orgtypes.AccountStatusActive},
+	{Id: aws.String("333333333333"), Name: aws.String("sandbox"), Status: orgtypes.AccountStatusSuspended},
+}
+
+var fakeChildren = map[string][]orgtypes.Child{
+	"r-root": {
+		{Id: aws.String("111111111111"), Type: orgtypes.ChildTypeAccount},
+		{Id: aws.String("ou-workloads"), Type: orgtypes.ChildTypeOrganizationalUnit},
+	},
+	"ou-workloads": {
+		{Id: aws.String("222222222222"), Type: orgtypes.ChildTypeAccount},
+		{Id: aws.String("ou-sandbox"), Type: orgtypes.ChildTypeOrganizationalUnit},
+	},
+	"ou-sandbox": {
+		{Id: aws.String("333333333333"), Type: orgtypes.ChildTypeAccount},
+	},
+}
+
+var fakeTags = map[string][]orgtypes.Tag{
+	"111111111111": {{Key: aws.String("env"), Value: aws.String("prod")}},
+	"222222222222": {{Key: aws.String("env"), Value: aws.String("prod")}, {Key: aws.String("team"), Value: aws.String("app")}},
+}
+
+func (f *fakeOrg) ListAccounts(ctx context.Context, in *org.ListAccountsInput, _ ...func(*org.Options)) (*org.ListAccountsOutput, error) {
+	atomic.AddInt32(&f.listAccountsCalls, 1)
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.denyListAccounts {
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "You don't have permissions to access this resource."}
+	}
+	if atomic.AddInt32(&f.failListAccounts, -1) >= 0 {
+		return nil, errors.New("throttled")
+	}
+	time.Sleep(5 * time.Millisecond)
+	page := 0
+	if in.NextToken != nil {
+		page, _ = strconv.Atoi(*in.NextToken)
+	}
+	out := &org.ListAccountsOutput{Accounts: fakeAccounts[page : page+1]}
+	if page+1 < len(fakeAccounts) {
+		out.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return out, nil
+}
+
+func (f *fakeOrg) ListAccountsForParent(ctx context.Context, in *org.ListAccountsForParentInput, _ ...func(*org.Options)) (*org.ListAccountsForParentOutput, error) {
+	var accounts []orgtypes.Account
+	for _, child := range fakeChildren[*in.ParentId] {
+		if child.Type != orgtypes.ChildTypeAccount {
+			continue
+		}
+		for _, account := range fakeAccounts {
+			if *account.Id == *child.Id {
+				accounts = append(accounts, account)
+			}
+		}
+	}
+	return &org.ListAccountsForParentOutput{Accounts: accounts}, nil
+}
+
+func (f *fakeOrg) ListRoots(ctx context.Context, in *org.ListRootsInput, _ ...func(*org.Options)) (*org.ListRootsOutput, error) {
+	return &org.ListRootsOutput{Roots: []orgtypes.Root{{Id: aws.String("r-root")}}}, nil
+}
+
+func (f *fakeOrg) ListChildren(ctx context.Context, in *org.ListChildrenInput, _ ...func(*org.Options)) (*org.ListChildrenOutput, error) {
+	atomic.AddInt32(&f.listChildrenCalls, 1)
+	var children []orgtypes.Child
+	for _, child := range fakeChildren[*in.ParentId] {
+		if child.Type == in.ChildType {
+			children = append(children, child)
+		}
+	}
+	return &org.ListChildrenOutput{Children: children}, nil
+}
+
+func (f *fakeOrg) ListTagsForResource(ctx context.Context, in *org.ListTagsForResourceInput, _ ...func(*org.Options)) (*org.ListTagsForResourceOutput, error) {
+	atomic.AddInt32(&f.listTagsCalls, 1)
+	return &org.ListTagsForResourceOutput{Tags: fakeTags[*in.ResourceId]}, nil
+}
+
+func newFakeInventory(fake *fakeOrg) *OrgInventory {
+	inv := NewOrgInventory()
+	inv.newClient = func(ctx context.Context, profileName string) (orgAPI, error) {
+		return fake, nil
+	}
+	return inv
+}
+
+func TestOrgInventoryPaginatesCachesAndDeduplicates(t *testing.T) {
+	fake := &fakeOrg{}
+	inv := newFakeInventory(fake)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			accounts, err := inv.Accounts(context.Background(), "default")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(accounts) != len(fakeAccounts) {
+				t.Errorf("expected %d accounts, got %d", len(fakeAccounts), len(accounts))
+			}
+		}()
+	}
+	wg.Wait()
+
+	account, err := inv.Account(context.Background(), "default", "333333333333")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account == nil || account.Status != "SUSPENDED" {
+		t.Errorf("unexpected account %+v", account)
+	}
+	if account, _ := inv.Account(context.Background(), "default", "444444444444"); account != nil {
+		t.Errorf("expected no account, got %+v", account)
+	}
+	if calls := atomic.LoadInt32(&fake.listAccountsCalls); calls != int32(len(fakeAccounts)) {
+		t.Errorf("expected one listing of %d pages, got %d calls", len(fakeAccounts), calls)
+	}
+}
+
+func TestOrgInventoryDoesNotCacheErrors(t *testing.T) {
+	inv := newFakeInventory(&fakeOrg{failListAccounts: 1})
+
+	if _, err := inv.Accounts(context.Background(), "default"); err == nil {
+		t.Fatal("expected an error")
+	}
+	accounts, err := inv.Accounts(context.Background(), "default")
+	if err != nil || len(accounts) != len(fakeAccounts) {
+		t.Errorf("expected the listing to be retried, got %v, %v", accounts, err)
+	}
+}
+
+func TestOrgInventorySharedListingOutlivesItsCaller(t *testing.T) {
+	fake := &fakeOrg{release: make(chan struct{})}
+	inv := newFakeInventory(fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := inv.Accounts(ctx, "default")
+		first <- err
+	}()
+	for atomic.LoadInt32(&fake.listAccountsCalls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled caller to stop waiting, got %v", err)
+	}
+
+	second := make(chan error)
+	go func() {
+		_, err := inv.Accounts(context.Background(), "default")
+		second <- err
+	}()
+	close(fake.release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the listing to complete for the other callers, got %s", err)
+	}
+	if calls := atomic.LoadInt32(&fake.listAccountsCalls); calls != int32(len(fakeAccounts)) {
+		t.Errorf("expected one listing of %d pages, got %d calls", len(fakeAccounts), calls)
+	}
+}
+
+func TestOrgInventoryFilterAccounts(t *testing.T) {
+	inv := newFakeInventory(&fakeOrg{})
+
+	cases := []struct {
+		name     string
+		filter   AccountFilter
+		expected []string
+	}{
+		{"all", AccountFilter{}, []string{"111111111111", "222222222222", "333333333333"}},
+		{"recursive unit", AccountFilter{ParentIDs: []string{"ou-workloads"}}, []string{"222222222222", "333333333333"}},
+		{"status", AccountFilter{ParentIDs: []string{"ou-workloads"}, Statuses: []string{AccountStatusActive}}, []string{"222222222222"}},
+		{"tags", AccountFilter{Tags: map[string]string{"env": "prod"}}, []string{"111111111111", "222222222222"}},
+		{"name", AccountFilter{NameRegex: regexp.MustCompile("^prod-")}, []string{"111111111111", "222222222222"}},
+		{"exclude", AccountFilter{ExcludeIDs: []string{"111111111111"}, NameRegex: regexp.MustCompile("^prod-")}, []string{"222222222222"}},
+	}
+	for _, c := range cases {
+		accounts, err := inv.FilterAccounts(context.Background(), "default", c.filter)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		var ids []string
+		for _, account := range accounts {
+			ids = append(ids, account.ID)
+		}
+		sort.Strings(ids)
+		if len(ids) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.name, c.expected, ids)
+				break
+			}
+		}
+	}
+
+	accounts, _ := inv.FilterAccounts(context.Background(), "default", AccountFilter{ExcludeIDs: []string{"111111111111", "222222222222"}, ResolveParents: true})
+	if len(accounts) != 1 || accounts[0].ParentID != "ou-sandbox" {
+		t.Errorf("expected the parent unit to be set, got %+v", accounts)
+	}
+}
+
+func TestOrgInventoryFilterAccountsOnlyFetchesWhatIsFiltered(t *testing.T) {
+	fake := &fakeOrg{}
+	inv := newFakeInventory(fake)
+
+	accounts, err := inv.FilterAccounts(context.Background(), "default", AccountFilter{Statuses: []string{AccountStatusActive}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 2 || accounts[0].ParentID != "" {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+	if calls := atomic.LoadInt32(&fake.listChildrenCalls); calls != 0 {
+		t.Errorf("expected no tree walk without a parent filter, got %d calls", calls)
+	}
+	if calls := atomic.LoadInt32(&fake.listTagsCalls); calls != 0 {
+		t.Errorf("expected no tags listed without a tag filter, got %d calls", calls)
+	}
+
+	accounts, err = inv.FilterAccounts(context.Background(), "default", AccountFilter{
+		NameRegex: regexp.MustCompile("^prod-app$"),
+		Tags:      map[string]string{"team": "app"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].ID != "222222222222" {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+	if calls := atomic.LoadInt32(&fake.listTagsCalls); calls != 1 {
+		t.Errorf("expected the tags of the accounts left by the other filters only, got %d calls", calls)
+	}
+}
+
+func TestOrgInventoryDelegatedAdministrator(t *testing.T) {
+	inv := newFakeInventory(&fakeOrg{denyListAccounts: true})
+
+	accounts, err := inv.Accounts(context.Background(), "security")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, account := range accounts {
+		ids = append(ids, account.ID)
+	}
+	sort.Strings(ids)
+	if len(ids) != 3 || ids[0] != "111111111111" || ids[2] != "333333333333" {
+		t.Errorf("expected every account from the tree walk, got %v", ids)
+	}
+
+	filtered, err := inv.FilterAccounts(context.Background(), "security", AccountFilter{
+		ParentIDs: []string{"ou-workloads"},
+		Statuses:  []string{AccountStatusActive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 1 || filtered[0].ID != "222222222222" {
+		t.Errorf("unexpected accounts %+v", filtered)
+	}
+}
@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// PolicyDocument is an IAM policy document, either an identity, a trust or a
+// resource policy.
+type PolicyDocument struct {
+	Version   string           `json:"Version"`
+	ID        string           `json:"Id,omitempty"`
+	Statement PolicyStatements `json:"Statement"`
+}
+
+// PolicyStatements accepts both the single statement and the list forms of
+// the Statement element.
+type PolicyStatements []PolicyStatement
+
+// PolicyStatement is a single statement of a PolicyDocument.
+type PolicyStatement struct {
+	Sid          string                           `json:"Sid,omitempty"`
+	Effect       string                           `json:"Effect"`
+	Principal    PolicyPrincipal                  `json:"Principal,omitempty"`
+	NotPrincipal PolicyPrincipal                  `json:"NotPrincipal,omitempty"`
+	Action       StringList                       `json:"Action,omitempty"`
+	NotAction    StringList                       `json:"NotAction,omitempty"`
+	Resource     StringList                       `json:"Resource,omitempty"`
+	NotResource  StringList                       `json:"NotResource,omitempty"`
+	Condition    map[string]map[string]StringList `json:"Condition,omitempty"`
+}
+
+// PolicyPrincipal maps a principal type such as AWS or Service to its
+// values. The wildcard principal "*" is represented as {"AWS": ["*"]}.
+type PolicyPrincipal map[string]StringList
+
+// StringList accepts both a single string and a list of strings, and is
+// written back as a single string when it has one element.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+func (s *PolicyStatements) UnmarshalJSON(data []byte) error {
+	var single PolicyStatement
+	if len(bytes.TrimSpace(data)) > 0 && bytes.TrimSpace(data)[0] == '{' {
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		*s = PolicyStatements{single}
+		return nil
+	}
+	var list []PolicyStatement
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+func (p *PolicyPrincipal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		*p = PolicyPrincipal{"AWS": StringList{wildcard}}
+		return nil
+	}
+	var principal map[string]StringList
+	if err := json.Unmarshal(data, &principal); err != nil {
+		return err
+	}
+	*p = principal
+	return nil
+}
+
+// ParsePolicyDocument parses a policy document as written by practitioners
+// or as returned, URL encoded, by IAM.
+func ParsePolicyDocument(document string) (*PolicyDocument, error) {
+	if !strings.HasPrefix(strings.TrimSpace(document), "{") {
+		decoded, err := url.QueryUnescape(document)
+		if err != nil {
+			return nil, err
+		}
+		document = decoded
+	}
+	var policy PolicyDocument
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// String returns the policy as indented JSON.
+func (p *PolicyDocument) String() string {
+	out, err := json.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// conditionValues returns the values of key in every condition of the
+// document's Allow statements, sorted and without duplicates.
+func (p *PolicyDocument) conditionValues(key string) []string {
+	seen := make(map[string]bool)
+	for _, statement := range p.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+		for _, condition := range statement.Condition {
+			for _, value := range condition[key] {
+				seen[value] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// principals returns the AWS principals of the document's Allow statements,
+// sorted and without duplicates.
+func (p *PolicyDocument) principals() []string {
+	seen := make(map[string]bool)
+	for _, statement := range p.Statement {
+		if statement.Effect != "Allow" {
+			continue
+		}
+		for _, value := range statement.Principal["AWS"] {
+			seen[value] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParsePolicyDocument(t *testing.T) {
+	trustPolicy := uptycsTrustPolicy("123456789012", "6a9375c1-47c0-470c-9217-d2f9d2d185f1")
+
+	for _, document := range []string{trustPolicy, url.QueryEscape(trustPolicy)} {
+		policy, err := ParsePolicyDocument(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if principals := policy.principals(); !reflect.DeepEqual(principals, []string{"arn:aws:iam::123456789012:root"}) {
+			t.Errorf("unexpected principals %v", principals)
+		}
+		if externalIDs := policy.conditionValues("sts:ExternalId"); !reflect.DeepEqual(externalIDs, []string{"6a9375c1-47c0-470c-9217-d2f9d2d185f1"}) {
+			t.Errorf("unexpected external IDs %v", externalIDs)
+		}
+	}
+}
+
+func TestParsePolicyDocumentShortForms(t *testing.T) {
+	policy, err := ParsePolicyDocument(`{
+		"Version": "2012-10-17",
+		"Statement": {
+			"Effect": "Allow",
+			"Principal": "*",
+			"Action": ["s3:GetObject", "s3:ListBucket"],
+			"Resource": "arn:aws:s3:::bucket/*"
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Statement) != 1 {
+		t.Fatalf("expected one statement, got %d", len(policy.Statement))
+	}
+	statement := policy.Statement[0]
+	if !reflect.DeepEqual(statement.Principal, PolicyPrincipal{"AWS": {"*"}}) {
+		t.Errorf("unexpected principal %v", statement.Principal)
+	}
+	if len(statement.Action) != 2 || len(statement.Resource) != 1 {
+		t.Errorf("unexpected statement %+v", statement)
+	}
+}
+
+func TestBuildReadOnlyPolicy(t *testing.T) {
+	policy, err := BuildReadOnlyPolicy(map[string]bool{FeatureCSPM: true, FeatureCIEM: false}, []PolicyStatement{
+		{Effect: "Allow", Action: StringList{"s3:GetObject", "kms:Decrypt", "s3:GetObject"}, Resource: StringList{"*"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Statement) != len(policyCatalog[FeatureCSPM])+1 {
+		t.Fatalf("unexpected statements %+v", policy.Statement)
+	}
+	document, err := policy.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckInlinePolicySize(document); err != nil {
+		t.Error(err)
+	}
+	parsed, err := ParsePolicyDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := parsed.Statement[len(parsed.Statement)-1]
+	if !reflect.DeepEqual(last.Action, StringList{"kms:Decrypt", "s3:GetObject"}) {
+		t.Errorf("expected sorted unique actions, got %v", last.Action)
+	}
+	again, _ := parsed.Canonical()
+	if again != document {
+		t.Errorf("canonical form is not stable:\n%s\n%s", document, again)
+	}
+
+	all := map[string]bool{}
+	for _, feature := range catalogOrder {
+		all[feature] = true
+	}
+	policy, _ = BuildReadOnlyPolicy(all, nil)
+	document, _ = policy.Canonical()
+	if err := CheckInlinePolicySize(document); err != nil {
+		t.Errorf("the full catalog must fit in an inline policy: %s", err)
+	}
+
+	if _, err := BuildReadOnlyPolicy(map[string]bool{"unknown": true}, nil); err == nil {
+		t.Error("expected an error for an unknown feature")
+	}
+	if _, err := BuildReadOnlyPolicy(map[string]bool{}, nil); err == nil {
+		t.Error("expected an error for an empty policy")
+	}
+	if _, err := BuildReadOnlyPolicy(map[string]bool{FeatureAgentlessScanning: true}, []PolicyStatement{
+		{Sid: "UptycsAgentlessKms", Effect: "Allow", Action: StringList{"kms:*"}, Resource: StringList{"*"}},
+	}); err == nil {
+		t.Error("expected an error for a statement ID of the catalog")
+	}
+	if _, err := BuildReadOnlyPolicy(map[string]bool{}, []PolicyStatement{
+		{Sid: "Extra", Effect: "Allow", Action: StringList{"s3:GetObject"}, Resource: StringList{"*"}},
+		{Sid: "Extra", Effect: "Allow", Action: StringList{"s3:ListBucket"}, Resource: StringList{"*"}},
+	}); err == nil {
+		t.Error("expected an error for a repeated statement ID")
+	}
+}
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultConcurrency is the number of accounts an organization wide
+// operation works on at once when no concurrency is configured.
+const DefaultConcurrency = 10
+
+// Unlimited disables the failure limit of ForEachAccount.
+const Unlimited = -1
+
+// ErrNotAttempted is the error of the accounts ForEachAccount skipped after
+// the failure limit was exceeded.
+var ErrNotAttempted = errors.New("not attempted, the operation stopped after too many failures in other accounts")
+
+// AccountResult is the outcome of an organization wide operation in one
+// account.
+type AccountResult struct {
+	AccountID string
+	RoleArn   string
+	Err       error
+}
+
+// NotAttempted reports whether the account was skipped.
+func (r AccountResult) NotAttempted() bool {
+	return errors.Is(r.Err, ErrNotAttempted)
+}
+
+// ForEachAccount calls fn for every account in accountIDs, running at most
+// concurrency calls at once. Once more than maxFailures calls failed, calls
+// already running complete but no new call starts, and the remaining
+// accounts fail with ErrNotAttempted. A maxFailures of Unlimited never stops.
+// The results are returned in the order of accountIDs.
+func ForEachAccount(ctx context.Context, accountIDs []string, concurrency int, maxFailures int, fn func(ctx context.Context, accountID string) (string, error)) []AccountResult {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	results := make([]AccountResult, len(accountIDs))
+	indexes := make(chan int)
+	var failures int32
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(accountIDs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				if maxFailures != Unlimited && int(atomic.LoadInt32(&failures)) > maxFailures {
+					results[index] = AccountResult{
+						AccountID: accountIDs[index],
+						Err:       ErrNotAttempted,
+					}
+					continue
+				}
+				roleArn, err := fn(ctx, accountIDs[index])
+				if err != nil {
+					atomic.AddInt32(&failures, 1)
+				}
+				results[index] = AccountResult{
+					AccountID: accountIDs[index],
+					RoleArn:   roleArn,
+					Err:       err,
+				}
+			}
+		}()
+	}
+	for index := range accountIDs {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// MaxFailures returns the number of failures tolerated among total accounts
+// by a threshold that is either a count or, when percent is set, a
+// percentage of total.
+func MaxFailures(threshold int64, percent bool, total int) int {
+	if percent {
+		return int(threshold * int64(total) / 100)
+	}
+	return int(threshold)
+}
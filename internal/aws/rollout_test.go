@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachAccount(t *testing.T) {
+	accountIDs := make([]string, 25)
+	for i := range accountIDs {
+		accountIDs[i] = fmt.Sprintf("%012d", i)
+	}
+
+	var running, maxRunning int32
+	results := ForEachAccount(context.Background(), accountIDs, 4, Unlimited, func(ctx context.Context, accountID string) (string, error) {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			seen := atomic.LoadInt32(&maxRunning)
+			if now <= seen || atomic.CompareAndSwapInt32(&maxRunning, seen, now) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		if accountID == accountIDs[7] {
+			return "", errors.New("denied")
+		}
+		return "arn:aws:iam::" + accountID + ":role/Uptycs", nil
+	})
+
+	if maxRunning > 4 {
+		t.Errorf("expected at most 4 concurrent calls, got %d", maxRunning)
+	}
+	if len(results) != len(accountIDs) {
+		t.Fatalf("expected %d results, got %d", len(accountIDs), len(results))
+	}
+	for i, result := range results {
+		if result.AccountID != accountIDs[i] {
+			t.Errorf("result %d is for %s, expected %s", i, result.AccountID, accountIDs[i])
+		}
+		if (result.Err != nil) != (i == 7) {
+			t.Errorf("unexpected error for %s: %v", result.AccountID, result.Err)
+		}
+		if result.Err == nil && result.RoleArn == "" {
+			t.Errorf("missing role ARN for %s", result.AccountID)
+		}
+	}
+}
+
+func TestForEachAccountMaxFailures(t *testing.T) {
+	accountIDs := make([]string, 20)
+	for i := range accountIDs {
+		accountIDs[i] = fmt.Sprintf("%012d", i)
+	}
+
+	var calls int32
+	results := ForEachAccount(context.Background(), accountIDs, 1, 2, func(ctx context.Context, accountID string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", errors.New("denied by scp")
+	})
+
+	// with one worker every failure is seen before the next account starts,
+	// with more workers the calls already running also complete
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+	notAttempted := 0
+	for _, result := range results {
+		if result.NotAttempted() {
+			notAttempted++
+		}
+	}
+	if notAttempted != 17 {
+		t.Errorf("expected 17 accounts not attempted, got %d", notAttempted)
+	}
+}
+
+func TestMaxFailures(t *testing.T) {
+	cases := []struct {
+		threshold int64
+		percent   bool
+		total     int
+		expected  int
+	}{
+		{0, false, 400, 0},
+		{5, false, 400, 5},
+		{10, true, 400, 40},
+		{10, true, 5, 0},
+		{100, true, 7, 7},
+	}
+	for _, c := range cases {
+		if got := MaxFailures(c.threshold, c.percent, c.total); got != c.expected {
+			t.Errorf("MaxFailures(%d, %v, %d) = %d, expected %d", c.threshold, c.percent, c.total, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// baselineActions are permissions of the ViewOnlyAccess and SecurityAudit
+// managed policies Uptycs cannot do without. Checking them detects a
+// permissions boundary or a service control policy filtering the managed
+// policies.
+var baselineActions = []string{
+	"cloudtrail:DescribeTrails",
+	"config:DescribeConfigurationRecorders",
+	"ec2:DescribeInstances",
+	"ec2:DescribeRegions",
+	"ec2:DescribeSecurityGroups",
+	"ec2:DescribeVpcs",
+	"iam:GetAccountPasswordPolicy",
+	"iam:ListPolicies",
+	"iam:ListRoles",
+	"iam:ListUsers",
+	"kms:ListKeys",
+	"rds:DescribeDBInstances",
+	"s3:GetBucketAcl",
+	"s3:GetBucketPolicy",
+	"s3:ListAllMyBuckets",
+}
+
+// simulateBatchSize is the number of actions simulated per request.
+const simulateBatchSize = 100
+
+// RequiredActions returns the actions the integration role must be allowed:
+// the baseline of the managed policies, the cloud security posture
+// management permissions of the catalog, and the actions granted on every
+// resource without conditions by policyDocument, when not empty. Actions
+// with wildcards are left out as they cannot be simulated.
+func RequiredActions(policyDocument string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, action := range baselineActions {
+		seen[action] = true
+	}
+	statements := append(PolicyStatements{}, policyCatalog[FeatureCSPM]...)
+	if policyDocument != "" {
+		policy, err := ParsePolicyDocument(policyDocument)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, policy.Statement...)
+	}
+	for _, statement := range statements {
+		if statement.Effect != "Allow" || len(statement.Condition) > 0 || !containsString(statement.Resource, "*") {
+			continue
+		}
+		for _, action := range statement.Action {
+			if !strings.ContainsAny(action, "*?") {
+				seen[action] = true
+			}
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+// SimulateRolePermissions evaluates actions for the role with the IAM policy
+// simulator, which takes its permissions boundary and the service control
+// policies of the organization into account. It returns the actions that
+// are not allowed, sorted.
+func SimulateRolePermissions(ctx context.Context, svc *iam.Client, roleArn string, actions []string) ([]string, error) {
+	missing := []string{}
+	for start := 0; start < len(actions); start += simulateBatchSize {
+		end := start + simulateBatchSize
+		if end > len(actions) {
+			end = len(actions)
+		}
+		paginator := iam.NewSimulatePrincipalPolicyPaginator(svc, &iam.SimulatePrincipalPolicyInput{
+			PolicySourceArn: &roleArn,
+			ActionNames:     actions[start:end],
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, newError(StepSimulatePolicy, err)
+			}
+			for _, result := range page.EvaluationResults {
+				if result.EvalDecision != iamtypes.PolicyEvaluationDecisionTypeAllowed && result.EvalActionName != nil {
+					missing = append(missing, *result.EvalActionName)
+				}
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequiredActions(t *testing.T) {
+	document := `{"Version":"2012-10-17","Statement":[
+		{"Effect":"Allow","Action":["sqs:ReceiveMessage","kms:ReEncrypt*"],"Resource":"*"},
+		{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::logs/*"},
+		{"Effect":"Allow","Action":"ec2:DeleteSnapshot","Resource":"*","Condition":{"StringEquals":{"aws:ResourceTag/CreatedBy":"Uptycs"}}},
+		{"Effect":"Deny","Action":"ec2:CreateSnapshot","Resource":"*"}]}`
+	actions, err := RequiredActions(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	has := make(map[string]bool)
+	for _, action := range actions {
+		has[action] = true
+	}
+	for _, action := range []string{"sqs:ReceiveMessage", "iam:ListRoles", "eks:ListClusters"} {
+		if !has[action] {
+			t.Errorf("expected %s to be required", action)
+		}
+	}
+	for _, action := range []string{"kms:ReEncrypt*", "s3:GetObject", "ec2:DeleteSnapshot", "ec2:CreateSnapshot"} {
+		if has[action] {
+			t.Errorf("expected %s not to be required", action)
+		}
+	}
+
+	baseline, err := RequiredActions("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(baseline, sortedKeys(func() map[string]bool {
+		seen := make(map[string]bool)
+		for _, action := range append(baselineActions, policyCatalog[FeatureCSPM][0].Action...) {
+			seen[action] = true
+		}
+		return seen
+	}())) {
+		t.Errorf("unexpected baseline %v", baseline)
+	}
+
+	if _, err := RequiredActions("not json"); err == nil {
+		t.Error("expected an error for an invalid document")
+	}
+}
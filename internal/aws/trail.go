@@ -0,0 +1,503 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	storage "github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	org "github.com/aws/aws-sdk-go-v2/service/organizations"
+)
+
+// keyDeletionWindowDays is the waiting period before a KMS key of a
+// destroyed trail is deleted. It is the shortest period KMS accepts.
+const keyDeletionWindowDays = 7
+
+// OrgTrailSpec describes an organization trail and its log bucket.
+type OrgTrailSpec struct {
+	ProfileName     string
+	TrailName       string
+	BucketName      string
+	BucketRegion    string
+	S3KeyPrefix     string
+	IntegrationName string
+	// ReaderAccountIDs are the accounts whose integration role, named
+	// IntegrationName, may read the logs.
+	ReaderAccountIDs []string
+	KMSEncryption    bool
+}
+
+// readerArns returns the ARNs of the integration roles allowed to read the
+// logs. They are listed explicitly: a role name alone could be created by
+// any administrator of any account of the organization.
+func (s OrgTrailSpec) readerArns() StringList {
+	arns := make(StringList, 0, len(s.ReaderAccountIDs))
+	for _, accountID := range s.ReaderAccountIDs {
+		arns = append(arns, "arn:aws:iam::"+accountID+":role/"+s.IntegrationName)
+	}
+	sort.Strings(arns)
+	return arns
+}
+
+// OrgTrail is an organization trail created by CreateOrgTrail.
+type OrgTrail struct {
+	TrailArn       string
+	BucketName     string
+	BucketRegion   string
+	S3KeyPrefix    string
+	KMSKeyArn      string
+	OrganizationID string
+}
+
+// LogPrefix returns the key prefix under which the trail delivers the logs
+// of the organization's accounts.
+func (t *OrgTrail) LogPrefix() string {
+	return logPrefix(t.S3KeyPrefix, t.OrganizationID)
+}
+
+// Drift describes how live, the trail as it is now, differs from t, the
+// trail as it was created: a log bucket, key prefix or KMS key changed
+// outside of Terraform. It returns nil when they match.
+func (t *OrgTrail) Drift(live *OrgTrail) []string {
+	var drift []string
+	if live.BucketName != t.BucketName {
+		drift = append(drift, fmt.Sprintf("logs are delivered to bucket %s instead of %s", live.BucketName, t.BucketName))
+	}
+	if prefix := strings.Trim(t.S3KeyPrefix, "/"); live.S3KeyPrefix != prefix {
+		drift = append(drift, fmt.Sprintf("logs are delivered under key prefix %q instead of %q", live.S3KeyPrefix, prefix))
+	}
+	switch {
+	case live.KMSKeyArn == t.KMSKeyArn:
+	case t.KMSKeyArn == "":
+		drift = append(drift, fmt.Sprintf("logs are encrypted with KMS key %s", live.KMSKeyArn))
+	case live.KMSKeyArn == "":
+		drift = append(drift, fmt.Sprintf("logs are no longer encrypted with KMS key %s", t.KMSKeyArn))
+	default:
+		drift = append(drift, fmt.Sprintf("logs are encrypted with KMS key %s instead of %s", live.KMSKeyArn, t.KMSKeyArn))
+	}
+	return drift
+}
+
+func logPrefix(s3KeyPrefix string, organizationID string) string {
+	prefix := "AWSLogs/" + organizationID + "/"
+	if s3KeyPrefix != "" {
+		prefix = strings.Trim(s3KeyPrefix, "/") + "/" + prefix
+	}
+	return prefix
+}
+
+func getAwsConfigForProfile(ctx context.Context, profileName string, regionCode string) (*aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(regionCode),
+		config.WithSharedConfigProfile(profileName),
+	)
+	if err != nil {
+		return nil, newError(StepLoadConfig, err)
+	}
+	return &cfg, nil
+}
+
+func trailArn(region string, accountID string, trailName string) string {
+	return fmt.Sprintf("arn:aws:cloudtrail:%s:%s:trail/%s", region, accountID, trailName)
+}
+
+// orgTrailBucketPolicy returns the bucket policy letting CloudTrail deliver
+// the logs of the trail, and letting the integration roles of the reader
+// accounts read them.
+func orgTrailBucketPolicy(spec OrgTrailSpec, accountID string, organizationID string) *PolicyDocument {
+	bucketArn := "arn:aws:s3:::" + spec.BucketName
+	trail := trailArn(spec.BucketRegion, accountID, spec.TrailName)
+	keyPrefix := ""
+	if spec.S3KeyPrefix != "" {
+		keyPrefix = strings.Trim(spec.S3KeyPrefix, "/") + "/"
+	}
+	cloudTrail := PolicyPrincipal{"Service": StringList{"cloudtrail.amazonaws.com"}}
+	integrationRoles := map[string]map[string]StringList{
+		"StringEquals": {
+			"aws:PrincipalOrgID": StringList{organizationID},
+			"aws:PrincipalArn":   spec.readerArns(),
+		},
+	}
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Sid:       "AWSCloudTrailAclCheck",
+				Effect:    "Allow",
+				Principal: cloudTrail,
+				Action:    StringList{"s3:GetBucketAcl"},
+				Resource:  StringList{bucketArn},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {"aws:SourceArn": StringList{trail}},
+				},
+			},
+			{
+				Sid:       "AWSCloudTrailWrite",
+				Effect:    "Allow",
+				Principal: cloudTrail,
+				Action:    StringList{"s3:PutObject"},
+				Resource: StringList{
+					bucketArn + "/" + keyPrefix + "AWSLogs/" + accountID + "/*",
+					bucketArn + "/" + keyPrefix + "AWSLogs/" + organizationID + "/*",
+				},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {
+						"s3:x-amz-acl":  StringList{"bucket-owner-full-control"},
+						"aws:SourceArn": StringList{trail},
+					},
+				},
+			},
+			{
+				Sid:       "UptycsReadLogs",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"AWS": StringList{"*"}},
+				Action:    StringList{"s3:GetObject"},
+				Resource:  StringList{bucketArn + "/" + keyPrefix + "AWSLogs/*"},
+				Condition: integrationRoles,
+			},
+			{
+				Sid:       "UptycsListBucket",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"AWS": StringList{"*"}},
+				Action:    StringList{"s3:GetBucketLocation", "s3:ListBucket"},
+				Resource:  StringList{bucketArn},
+				Condition: integrationRoles,
+			},
+		},
+	}
+}
+
+// orgTrailKeyPolicy returns the policy of the KMS key encrypting the logs of
+// the trail. CloudTrail encrypts with the key and the integration roles of
+// the reader accounts decrypt the logs.
+func orgTrailKeyPolicy(spec OrgTrailSpec, accountID string, organizationID string) *PolicyDocument {
+	trail := trailArn(spec.BucketRegion, accountID, spec.TrailName)
+	cloudTrail := PolicyPrincipal{"Service": StringList{"cloudtrail.amazonaws.com"}}
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Sid:       "EnableIAMPolicies",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"AWS": StringList{"arn:aws:iam::" + accountID + ":root"}},
+				Action:    StringList{"kms:*"},
+				Resource:  StringList{"*"},
+			},
+			{
+				Sid:       "AWSCloudTrailEncrypt",
+				Effect:    "Allow",
+				Principal: cloudTrail,
+				Action:    StringList{"kms:GenerateDataKey*"},
+				Resource:  StringList{"*"},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {"aws:SourceArn": StringList{trail}},
+					"StringLike":   {"kms:EncryptionContext:aws:cloudtrail:arn": StringList{"arn:aws:cloudtrail:*:" + accountID + ":trail/*"}},
+				},
+			},
+			{
+				Sid:       "AWSCloudTrailDescribeKey",
+				Effect:    "Allow",
+				Principal: cloudTrail,
+				Action:    StringList{"kms:DescribeKey"},
+				Resource:  StringList{"*"},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {"aws:SourceArn": StringList{trail}},
+				},
+			},
+			{
+				Sid:       "UptycsDecryptLogs",
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"AWS": StringList{"*"}},
+				Action:    StringList{"kms:Decrypt"},
+				Resource:  StringList{"*"},
+				Condition: map[string]map[string]StringList{
+					"StringEquals": {
+						"aws:PrincipalOrgID": StringList{organizationID},
+						"aws:PrincipalArn":   spec.readerArns(),
+					},
+					"Null": {"kms:EncryptionContext:aws:cloudtrail:arn": StringList{"false"}},
+				},
+			},
+		},
+	}
+}
+
+// CreateOrgTrail creates the log bucket, the optional KMS key and the
+// organization trail of spec, and starts logging. The trail lives in the
+// region of its bucket. When a step fails, what was created before is
+// removed again.
+func CreateOrgTrail(ctx context.Context, spec OrgTrailSpec) (*OrgTrail, error) {
+	var created journal
+	fail := func(step string, err error) (*OrgTrail, error) {
+		awsErr := wrapError(step, err)
+		awsErr.CleanupErr = created.rollback(ctx)
+		return nil, awsErr
+	}
+
+	cfg, err := getAwsConfigForProfile(ctx, spec.ProfileName, spec.BucketRegion)
+	if err != nil {
+		return nil, err
+	}
+	accountID, err := getCallerAccountID(ctx, *cfg)
+	if err != nil {
+		return nil, err
+	}
+	orgClient, err := GetOrgClient(ctx, spec.ProfileName, "")
+	if err != nil {
+		return nil, err
+	}
+	organization, err := orgClient.DescribeOrganization(ctx, &org.DescribeOrganizationInput{})
+	if err != nil {
+		return nil, newError(StepDescribeOrg, err)
+	}
+	if organization.Organization == nil || organization.Organization.Id == nil {
+		return nil, newError(StepDescribeOrg, fmt.Errorf("invalid DescribeOrganizationOutput"))
+	}
+	trail := &OrgTrail{
+		BucketName:     spec.BucketName,
+		BucketRegion:   spec.BucketRegion,
+		S3KeyPrefix:    spec.S3KeyPrefix,
+		OrganizationID: *organization.Organization.Id,
+	}
+
+	s3Client := storage.NewFromConfig(*cfg)
+	kmsClient := kms.NewFromConfig(*cfg)
+	trailClient := cloudtrail.NewFromConfig(*cfg)
+
+	if spec.KMSEncryption {
+		policy := orgTrailKeyPolicy(spec, accountID, trail.OrganizationID).String()
+		key, err := kmsClient.CreateKey(ctx, &kms.CreateKeyInput{
+			Description: aws.String("Encrypts the logs of the CloudTrail trail " + spec.TrailName),
+			Policy:      &policy,
+		})
+		if err != nil {
+			return fail(StepCreateKey, err)
+		}
+		if key.KeyMetadata == nil || key.KeyMetadata.Arn == nil {
+			return fail(StepCreateKey, fmt.Errorf("invalid CreateKeyOutput"))
+		}
+		trail.KMSKeyArn = *key.KeyMetadata.Arn
+		created.record(StepCreateKey, func(ctx context.Context) error {
+			return scheduleKeyDeletion(ctx, kmsClient, trail.KMSKeyArn)
+		})
+	}
+
+	bucket := &storage.CreateBucketInput{Bucket: &spec.BucketName}
+	if spec.BucketRegion != "us-east-1" {
+		bucket.CreateBucketConfiguration = &s3types.CreateBucketConfiguration{
+			LocationConstraint: s3types.BucketLocationConstraint(spec.BucketRegion),
+		}
+	}
+	if _, err := s3Client.CreateBucket(ctx, bucket); err != nil {
+		return fail(StepCreateBucket, err)
+	}
+	created.record(StepCreateBucket, func(ctx context.Context) error {
+		return deleteBucket(ctx, s3Client, spec.BucketName, true)
+	})
+	if _, err := s3Client.PutPublicAccessBlock(ctx, &storage.PutPublicAccessBlockInput{
+		Bucket: &spec.BucketName,
+		PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	}); err != nil {
+		return fail(StepCreateBucket, err)
+	}
+	policy := orgTrailBucketPolicy(spec, accountID, trail.OrganizationID).String()
+	if _, err := s3Client.PutBucketPolicy(ctx, &storage.PutBucketPolicyInput{
+		Bucket: &spec.BucketName,
+		Policy: &policy,
+	}); err != nil {
+		return fail(StepPutBucketPolicy, err)
+	}
+
+	input := &cloudtrail.CreateTrailInput{
+		Name:                       &spec.TrailName,
+		S3BucketName:               &spec.BucketName,
+		IsOrganizationTrail:        aws.Bool(true),
+		IsMultiRegionTrail:         aws.Bool(true),
+		IncludeGlobalServiceEvents: aws.Bool(true),
+		EnableLogFileValidation:    aws.Bool(true),
+	}
+	if spec.S3KeyPrefix != "" {
+		input.S3KeyPrefix = aws.String(strings.Trim(spec.S3KeyPrefix, "/"))
+	}
+	if trail.KMSKeyArn != "" {
+		input.KmsKeyId = &trail.KMSKeyArn
+	}
+	out, err := trailClient.CreateTrail(ctx, input)
+	if err != nil {
+		return fail(StepCreateTrail, err)
+	}
+	if out.TrailARN == nil {
+		return fail(StepCreateTrail, fmt.Errorf("invalid CreateTrailOutput for %s", spec.TrailName))
+	}
+	trail.TrailArn = *out.TrailARN
+	created.record(StepCreateTrail, func(ctx context.Context) error {
+		return deleteTrail(ctx, trailClient, trail.TrailArn)
+	})
+
+	if _, err := trailClient.StartLogging(ctx, &cloudtrail.StartLoggingInput{Name: &trail.TrailArn}); err != nil {
+		return fail(StepStartLogging, err)
+	}
+	return trail, nil
+}
+
+// UpdateOrgTrailReaders rewrites the policies of the bucket and of the KMS
+// key of trail so that the integration roles of spec.ReaderAccountIDs, and
+// only those, can read the logs.
+func UpdateOrgTrailReaders(ctx context.Context, spec OrgTrailSpec, trail OrgTrail) error {
+	cfg, err := getAwsConfigForProfile(ctx, spec.ProfileName, spec.BucketRegion)
+	if err != nil {
+		return err
+	}
+	accountID, err := getCallerAccountID(ctx, *cfg)
+	if err != nil {
+		return err
+	}
+	if trail.KMSKeyArn != "" {
+		policy := orgTrailKeyPolicy(spec, accountID, trail.OrganizationID).String()
+		_, err := kms.NewFromConfig(*cfg).PutKeyPolicy(ctx, &kms.PutKeyPolicyInput{
+			KeyId:      &trail.KMSKeyArn,
+			PolicyName: aws.String("default"),
+			Policy:     &policy,
+		})
+		if err != nil {
+			return newError(StepPutKeyPolicy, err)
+		}
+	}
+	policy := orgTrailBucketPolicy(spec, accountID, trail.OrganizationID).String()
+	_, err = storage.NewFromConfig(*cfg).PutBucketPolicy(ctx, &storage.PutBucketPolicyInput{
+		Bucket: &spec.BucketName,
+		Policy: &policy,
+	})
+	return newError(StepPutBucketPolicy, err)
+}
+
+// GetOrgTrail returns the trail named trailName of the region, or nil when
+// it does not exist.
+func GetOrgTrail(ctx context.Context, profileName string, regionCode string, trailName string) (*OrgTrail, error) {
+	cfg, err := getAwsConfigForProfile(ctx, profileName, regionCode)
+	if err != nil {
+		return nil, err
+	}
+	out, err := cloudtrail.NewFromConfig(*cfg).GetTrail(ctx, &cloudtrail.GetTrailInput{Name: &trailName})
+	if err != nil {
+		var notFound *cloudtrailtypes.TrailNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, newError(StepGetTrail, err)
+	}
+	if out.Trail == nil || out.Trail.TrailARN == nil {
+		return nil, newError(StepGetTrail, fmt.Errorf("invalid GetTrailOutput for %s", trailName))
+	}
+	return &OrgTrail{
+		TrailArn:     *out.Trail.TrailARN,
+		BucketName:   aws.ToString(out.Trail.S3BucketName),
+		BucketRegion: aws.ToString(out.Trail.HomeRegion),
+		S3KeyPrefix:  aws.ToString(out.Trail.S3KeyPrefix),
+		KMSKeyArn:    aws.ToString(out.Trail.KmsKeyId),
+	}, nil
+}
+
+// DeleteOrgTrail deletes the trail, schedules the deletion of its KMS key and
+// deletes its log bucket. The bucket must be empty unless forceDestroy is
+// set, in which case every log is deleted first. Resources that no longer
+// exist are skipped.
+func DeleteOrgTrail(ctx context.Context, profileName string, trail OrgTrail, forceDestroy bool) error {
+	cfg, err := getAwsConfigForProfile(ctx, profileName, trail.BucketRegion)
+	if err != nil {
+		return err
+	}
+	if err := deleteTrail(ctx, cloudtrail.NewFromConfig(*cfg), trail.TrailArn); err != nil {
+		return newError(StepDeleteTrail, err)
+	}
+	if err := deleteBucket(ctx, storage.NewFromConfig(*cfg), trail.BucketName, forceDestroy); err != nil {
+		return newError(StepDeleteBucket, err)
+	}
+	if trail.KMSKeyArn != "" {
+		if err := scheduleKeyDeletion(ctx, kms.NewFromConfig(*cfg), trail.KMSKeyArn); err != nil {
+			return newError(StepDeleteKey, err)
+		}
+	}
+	return nil
+}
+
+func deleteTrail(ctx context.Context, svc *cloudtrail.Client, name string) error {
+	_, err := svc.DeleteTrail(ctx, &cloudtrail.DeleteTrailInput{Name: &name})
+	var notFound *cloudtrailtypes.TrailNotFoundException
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
+// scheduleKeyDeletion schedules the deletion of the key. A key already
+// pending deletion or deleted is left alone.
+func scheduleKeyDeletion(ctx context.Context, svc *kms.Client, keyID string) error {
+	_, err := svc.ScheduleKeyDeletion(ctx, &kms.ScheduleKeyDeletionInput{
+		KeyId:               &keyID,
+		PendingWindowInDays: aws.Int32(keyDeletionWindowDays),
+	})
+	var notFound *kmstypes.NotFoundException
+	var invalidState *kmstypes.KMSInvalidStateException
+	if errors.As(err, &notFound) || errors.As(err, &invalidState) {
+		return nil
+	}
+	return err
+}
+
+// deleteBucket deletes the bucket, first deleting every object when empty
+// is set.
+func deleteBucket(ctx context.Context, svc *storage.Client, bucketName string, empty bool) error {
+	if empty {
+		paginator := storage.NewListObjectVersionsPaginator(svc, &storage.ListObjectVersionsInput{Bucket: &bucketName})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				if IsKind(err, ErrBucketNotFound) {
+					return nil
+				}
+				return err
+			}
+			var objects []s3types.ObjectIdentifier
+			for _, version := range page.Versions {
+				objects = append(objects, s3types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+			}
+			for _, marker := range page.DeleteMarkers {
+				objects = append(objects, s3types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+			}
+			if len(objects) == 0 {
+				continue
+			}
+			out, err := svc.DeleteObjects(ctx, &storage.DeleteObjectsInput{
+				Bucket: &bucketName,
+				Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return err
+			}
+			if len(out.Errors) > 0 {
+				return fmt.Errorf("unable to delete %s from %s: %s", aws.ToString(out.Errors[0].Key), bucketName, aws.ToString(out.Errors[0].Message))
+			}
+		}
+	}
+	_, err := svc.DeleteBucket(ctx, &storage.DeleteBucketInput{Bucket: &bucketName})
+	if IsKind(err, ErrBucketNotFound) {
+		return nil
+	}
+	return err
+}
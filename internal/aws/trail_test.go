@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrgTrailBucketPolicy(t *testing.T) {
+	spec := OrgTrailSpec{
+		TrailName:       "uptycs",
+		BucketName:      "org-logs",
+		BucketRegion:    "us-west-2",
+		S3KeyPrefix:     "/trails/",
+		IntegrationName: "UptycsIntegration",
+		// unsorted on purpose, the policy lists them sorted
+		ReaderAccountIDs: []string{"222222222222", "111111111111"},
+	}
+	policy, err := ParsePolicyDocument(orgTrailBucketPolicy(spec, "123456789012", "o-abcdef1234").String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := make(map[string]PolicyStatement)
+	for _, statement := range policy.Statement {
+		statements[statement.Sid] = statement
+	}
+	write := statements["AWSCloudTrailWrite"]
+	expected := StringList{
+		"arn:aws:s3:::org-logs/trails/AWSLogs/123456789012/*",
+		"arn:aws:s3:::org-logs/trails/AWSLogs/o-abcdef1234/*",
+	}
+	if !reflect.DeepEqual(write.Resource, expected) {
+		t.Errorf("unexpected write resources %v", write.Resource)
+	}
+	if sourceArn := write.Condition["StringEquals"]["aws:SourceArn"]; !reflect.DeepEqual(sourceArn, StringList{"arn:aws:cloudtrail:us-west-2:123456789012:trail/uptycs"}) {
+		t.Errorf("unexpected source ARN %v", sourceArn)
+	}
+	read := statements["UptycsReadLogs"]
+	if !reflect.DeepEqual(read.Resource, StringList{"arn:aws:s3:::org-logs/trails/AWSLogs/*"}) {
+		t.Errorf("unexpected read resources %v", read.Resource)
+	}
+	for _, sid := range []string{"UptycsReadLogs", "UptycsListBucket"} {
+		checkReaderCondition(t, sid, statements[sid])
+	}
+	if orgIDs := policy.conditionValues("aws:PrincipalOrgID"); !reflect.DeepEqual(orgIDs, []string{"o-abcdef1234"}) {
+		t.Errorf("unexpected organization IDs %v", orgIDs)
+	}
+}
+
+func TestOrgTrailKeyPolicy(t *testing.T) {
+	spec := OrgTrailSpec{
+		TrailName:        "uptycs",
+		BucketRegion:     "us-west-2",
+		IntegrationName:  "UptycsIntegration",
+		ReaderAccountIDs: []string{"222222222222", "111111111111"},
+	}
+	policy, err := ParsePolicyDocument(orgTrailKeyPolicy(spec, "123456789012", "o-abcdef1234").String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range policy.Statement {
+		if statement.Sid == "UptycsDecryptLogs" {
+			checkReaderCondition(t, statement.Sid, statement)
+			return
+		}
+	}
+	t.Error("expected a UptycsDecryptLogs statement")
+}
+
+// checkReaderCondition pins the condition granting the logs to the
+// integration roles: the explicit roles of the reader accounts within the
+// organization, never a role name any member account could create.
+func checkReaderCondition(t *testing.T, sid string, statement PolicyStatement) {
+	t.Helper()
+	expected := map[string]StringList{
+		"aws:PrincipalOrgID": {"o-abcdef1234"},
+		"aws:PrincipalArn":   {"arn:aws:iam::111111111111:role/UptycsIntegration", "arn:aws:iam::222222222222:role/UptycsIntegration"},
+	}
+	if !reflect.DeepEqual(statement.Condition["StringEquals"], expected) {
+		t.Errorf("%s: unexpected reader condition %v", sid, statement.Condition["StringEquals"])
+	}
+	for operator, keys := range statement.Condition {
+		if operator == "StringEquals" {
+			continue
+		}
+		if _, found := keys["aws:PrincipalArn"]; found {
+			t.Errorf("%s: unexpected %s condition on aws:PrincipalArn", sid, operator)
+		}
+	}
+}
+
+func TestLogPrefix(t *testing.T) {
+	for s3KeyPrefix, expected := range map[string]string{
+		"":        "AWSLogs/o-abcdef1234/",
+		"trails":  "trails/AWSLogs/o-abcdef1234/",
+		"/a/b/":   "a/b/AWSLogs/o-abcdef1234/",
+		"a/b/c/d": "a/b/c/d/AWSLogs/o-abcdef1234/",
+	} {
+		if prefix := logPrefix(s3KeyPrefix, "o-abcdef1234"); prefix != expected {
+			t.Errorf("logPrefix(%q) = %q, expected %q", s3KeyPrefix, prefix, expected)
+		}
+	}
+}
+
+func TestOrgTrailDrift(t *testing.T) {
+	created := &OrgTrail{
+		BucketName:  "uptycs-logs",
+		S3KeyPrefix: "/trails/",
+		KMSKeyArn:   "arn:aws:kms:us-east-1:123456789012:key/1234",
+	}
+	for _, tc := range []struct {
+		name  string
+		live  OrgTrail
+		drift []string
+	}{
+		{
+			name: "unchanged",
+			live: OrgTrail{BucketName: "uptycs-logs", S3KeyPrefix: "trails", KMSKeyArn: "arn:aws:kms:us-east-1:123456789012:key/1234"},
+		},
+		{
+			name:  "bucket",
+			live:  OrgTrail{BucketName: "other-logs", S3KeyPrefix: "trails", KMSKeyArn: "arn:aws:kms:us-east-1:123456789012:key/1234"},
+			drift: []string{"logs are delivered to bucket other-logs instead of uptycs-logs"},
+		},
+		{
+			name:  "prefix",
+			live:  OrgTrail{BucketName: "uptycs-logs", KMSKeyArn: "arn:aws:kms:us-east-1:123456789012:key/1234"},
+			drift: []string{`logs are delivered under key prefix "" instead of "trails"`},
+		},
+		{
+			name:  "key removed",
+			live:  OrgTrail{BucketName: "uptycs-logs", S3KeyPrefix: "trails"},
+			drift: []string{"logs are no longer encrypted with KMS key arn:aws:kms:us-east-1:123456789012:key/1234"},
+		},
+		{
+			name:  "key replaced",
+			live:  OrgTrail{BucketName: "uptycs-logs", S3KeyPrefix: "trails", KMSKeyArn: "arn:aws:kms:us-east-1:123456789012:key/5678"},
+			drift: []string{"logs are encrypted with KMS key arn:aws:kms:us-east-1:123456789012:key/5678 instead of arn:aws:kms:us-east-1:123456789012:key/1234"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if drift := created.Drift(&tc.live); !reflect.DeepEqual(drift, tc.drift) {
+				t.Errorf("Drift() = %q, expected %q", drift, tc.drift)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"crypto/rand"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var principalArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:(iam|sts)::([0-9]{12}):.+$`)
+
+// TrustCondition is a condition the callers assuming the integration role
+// must meet on top of the external ID, such as the IpAddress test of
+// aws:SourceIp.
+type TrustCondition struct {
+	Test     string
+	Variable string
+	Values   []string
+}
+
+// Trust describes who may assume the integration role. Each principal must
+// pass one of the external IDs and meet every condition.
+type Trust struct {
+	// UptAccountIDs are Uptycs accounts trusted as a whole, through their
+	// root principal.
+	UptAccountIDs []string
+
+	// PrincipalArns are specific Uptycs principals, such as the role a
+	// tenant assumes integration roles from.
+	PrincipalArns []string
+
+	// ExternalIDs lists the current external ID first, followed by the
+	// previous one while it is rotated.
+	ExternalIDs []string
+
+	Conditions []TrustCondition
+}
+
+// Validate checks the principals and conditions of the trust. The external
+// IDs are checked along with the trust policy.
+func (t Trust) Validate() error {
+	if len(t.UptAccountIDs) == 0 && len(t.PrincipalArns) == 0 {
+		return fmt.Errorf("trust has no Uptycs account nor principal")
+	}
+	for _, accountID := range t.UptAccountIDs {
+		if !accountIDPattern.MatchString(accountID) {
+			return fmt.Errorf("upt_account_id %q is not a 12 digit AWS account ID", accountID)
+		}
+	}
+	for _, principalArn := range t.PrincipalArns {
+		if !principalArnPattern.MatchString(principalArn) {
+			return fmt.Errorf("principal %q is not the ARN of an IAM or STS principal", principalArn)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, condition := range t.Conditions {
+		if condition.Test == "" || condition.Variable == "" || len(condition.Values) == 0 {
+			return fmt.Errorf("trust condition needs a test, a variable and values")
+		}
+		if strings.EqualFold(condition.Variable, "sts:ExternalId") {
+			return fmt.Errorf("trust condition cannot test sts:ExternalId, set external_id instead")
+		}
+		key := condition.Test + " " + condition.Variable
+		if seen[key] {
+			return fmt.Errorf("trust condition %s on %s is set twice, list its values in a single condition", condition.Test, condition.Variable)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// policy returns the trust policy letting the principals assume the role.
+func (t Trust) policy() *PolicyDocument {
+	principals := StringList{}
+	for _, accountID := range t.UptAccountIDs {
+		principals = append(principals, "arn:aws:iam::"+accountID+":root")
+	}
+	principals = append(principals, t.PrincipalArns...)
+
+	conditions := map[string]map[string]StringList{
+		"StringEquals": {"sts:ExternalId": StringList(t.ExternalIDs)},
+	}
+	for _, condition := range t.Conditions {
+		if conditions[condition.Test] == nil {
+			conditions[condition.Test] = make(map[string]StringList)
+		}
+		conditions[condition.Test][condition.Variable] = StringList(condition.Values)
+	}
+
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Effect:    "Allow",
+				Principal: PolicyPrincipal{"AWS": principals},
+				Action:    StringList{"sts:AssumeRole"},
+				Condition: conditions,
+			},
+		},
+	}
+}
+
+// trusts reports whether principal, as found in a trust policy, covers the
+// trusted account or principal ARN: an account is covered by itself, its
+// root and its roles, a principal ARN by itself and the root of its account.
+func trusts(principal string, trusted string) bool {
+	if principal == trusted {
+		return true
+	}
+	if accountIDPattern.MatchString(trusted) {
+		return principal == "arn:aws:iam::"+trusted+":root" || isUptAccountRole(principal, trusted)
+	}
+	if match := principalArnPattern.FindStringSubmatch(trusted); match != nil {
+		return principal == match[2] || principal == "arn:aws:iam::"+match[2]+":root"
+	}
+	return false
+}
+
+// GenerateExternalID returns a random version 4 UUID for the external ID of
+// an integration role, read from the cryptographic random source so that it
+// cannot be guessed.
+func GenerateExternalID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate external ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// uptycsTrustPolicy is the trust policy of an integration role trusting the
+// Uptycs account with the external IDs.
+func uptycsTrustPolicy(uptAccountID string, externalIDs ...string) string {
+	return Trust{UptAccountIDs: []string{uptAccountID}, ExternalIDs: externalIDs}.policy().String()
+}
+
+func TestTrustPolicy(t *testing.T) {
+	trust := Trust{
+		UptAccountIDs: []string{"123456789013", "123456789014"},
+		PrincipalArns: []string{"arn:aws:iam::123456789015:role/Uptycs"},
+		ExternalIDs:   []string{"6a9375c1-47c0-470c-9217-d2f9d2d185f1"},
+		Conditions: []TrustCondition{
+			{Test: "IpAddress", Variable: "aws:SourceIp", Values: []string{"203.0.113.0/24"}},
+			{Test: "StringEquals", Variable: "aws:PrincipalOrgID", Values: []string{"o-abcdefghij"}},
+		},
+	}
+	if err := trust.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	document := trust.policy().String()
+	policy, err := ParsePolicyDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"arn:aws:iam::123456789013:root", "arn:aws:iam::123456789014:root", "arn:aws:iam::123456789015:role/Uptycs"}
+	if principals := policy.principals(); !reflect.DeepEqual(principals, expected) {
+		t.Errorf("unexpected principals %v", principals)
+	}
+	condition := policy.Statement[0].Condition
+	if !reflect.DeepEqual(condition["StringEquals"]["aws:PrincipalOrgID"], StringList{"o-abcdefghij"}) ||
+		!reflect.DeepEqual(condition["StringEquals"]["sts:ExternalId"], StringList{"6a9375c1-47c0-470c-9217-d2f9d2d185f1"}) ||
+		!reflect.DeepEqual(condition["IpAddress"]["aws:SourceIp"], StringList{"203.0.113.0/24"}) {
+		t.Errorf("unexpected conditions %v", condition)
+	}
+	if err := checkTrustPolicy(document, trust); err != nil {
+		t.Errorf("generated trust policy rejected: %v", err)
+	}
+
+	// Each trusted account and principal must be able to assume the role.
+	err = checkTrustPolicy(uptycsTrustPolicy("123456789013", trust.ExternalIDs...), trust)
+	if err == nil || !strings.Contains(err.Error(), "123456789014") {
+		t.Errorf("expected account 123456789014 not to be trusted, got %v", err)
+	}
+	// A principal is trusted through the root of its account.
+	narrowed := Trust{PrincipalArns: []string{"arn:aws:iam::123456789013:role/Uptycs"}, ExternalIDs: trust.ExternalIDs}
+	if err := checkTrustPolicy(uptycsTrustPolicy("123456789013", trust.ExternalIDs...), narrowed); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTrustValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		trust Trust
+		err   string
+	}{
+		{"no principal", Trust{}, "no Uptycs account"},
+		{"short account", Trust{UptAccountIDs: []string{"12345"}}, "12 digit"},
+		{"principal not an ARN", Trust{PrincipalArns: []string{"Uptycs"}}, "not the ARN"},
+		{"external ID condition", Trust{UptAccountIDs: []string{"123456789013"}, Conditions: []TrustCondition{
+			{Test: "StringEquals", Variable: "sts:ExternalId", Values: []string{"other"}},
+		}}, "external_id"},
+		{"condition without values", Trust{UptAccountIDs: []string{"123456789013"}, Conditions: []TrustCondition{
+			{Test: "IpAddress", Variable: "aws:SourceIp"},
+		}}, "needs a test"},
+		{"condition twice", Trust{UptAccountIDs: []string{"123456789013"}, Conditions: []TrustCondition{
+			{Test: "IpAddress", Variable: "aws:SourceIp", Values: []string{"203.0.113.0/24"}},
+			{Test: "IpAddress", Variable: "aws:SourceIp", Values: []string{"198.51.100.0/24"}},
+		}}, "set twice"},
+	}
+	for _, c := range cases {
+		err := c.trust.Validate()
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestGenerateExternalID(t *testing.T) {
+	first, err := GenerateExternalID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GenerateExternalID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("expected different external IDs, got %s twice", first)
+	}
+	if len(first) != 36 || first[14] != '4' {
+		t.Errorf("expected a version 4 UUID, got %s", first)
+	}
+	if err := checkTrustPolicy(uptycsTrustPolicy("123456789013", first), Trust{UptAccountIDs: []string{"123456789013"}, ExternalIDs: []string{first}}); err != nil {
+		t.Errorf("generated external ID rejected: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// Verification statuses of an integration role.
+const (
+	// VerificationVerified means the verifier assumed the role with the
+	// external ID, and could not without it.
+	VerificationVerified = "verified"
+	// VerificationPolicyValid means the trust policy passed the static
+	// checks and no verifier was configured to assume the role.
+	VerificationPolicyValid = "policy_valid"
+	// VerificationFailed means a check failed, see the verification error.
+	VerificationFailed = "failed"
+)
+
+// verifySessionName is the session name of the verifier, so that its
+// sessions can be told apart in CloudTrail.
+const verifySessionName = "uptycscspm-trust-verification"
+
+var (
+	accountIDPattern  = regexp.MustCompile(`^[0-9]{12}$`)
+	externalIDPattern = regexp.MustCompile(`^[\w+=,.@:/-]+$`)
+)
+
+// TrustVerification describes the trust an integration role is expected to
+// grant to Uptycs.
+type TrustVerification struct {
+	RoleName string
+	Trust    Trust
+
+	// VerifierProfile is a profile with the credentials of a trusted Uptycs
+	// principal. When set, the role is assumed with the current external ID
+	// to prove the trust works.
+	VerifierProfile string
+}
+
+// assumeRetries and assumeRetryWait bound how long the verifier waits for a
+// new trust policy to propagate through IAM.
+const (
+	assumeRetries   = 6
+	assumeRetryWait = 5 * time.Second
+)
+
+// checkTrustPolicy statically evaluates a trust policy document: it must let
+// each Uptycs account and principal of the trust assume the role with each
+// of the external IDs, and must not let anyone assume it without an external
+// ID.
+func checkTrustPolicy(document string, trust Trust) error {
+	if err := trust.Validate(); err != nil {
+		return err
+	}
+	if len(trust.ExternalIDs) == 0 {
+		return fmt.Errorf("external_id is required")
+	}
+	for _, externalID := range trust.ExternalIDs {
+		if len(externalID) < 2 || len(externalID) > 1224 || !externalIDPattern.MatchString(externalID) {
+			return fmt.Errorf("external_id must have 2 to 1224 letters, digits or any of +=,.@:/-")
+		}
+	}
+	policy, err := ParsePolicyDocument(document)
+	if err != nil {
+		return fmt.Errorf("trust policy is not valid JSON: %w", err)
+	}
+
+	// trusted maps the accounts and principal ARNs of the trust to the
+	// external IDs they can assume the role with.
+	targets := append(append([]string{}, trust.UptAccountIDs...), trust.PrincipalArns...)
+	trusted := make(map[string]map[string]bool)
+	for _, statement := range policy.Statement {
+		if !allowsAssumeRole(statement) {
+			continue
+		}
+		if statement.Effect != "Allow" {
+			if len(statement.Condition) == 0 {
+				return fmt.Errorf("trust policy denies sts:AssumeRole to every caller")
+			}
+			continue
+		}
+		conditionIDs := StringList{}
+		for operator, condition := range statement.Condition {
+			if operator == "StringEquals" {
+				conditionIDs = append(conditionIDs, condition["sts:ExternalId"]...)
+			}
+		}
+		if len(conditionIDs) == 0 {
+			return fmt.Errorf("trust policy lets %v assume the role without an external ID", statement.Principal["AWS"])
+		}
+		for _, principal := range statement.Principal["AWS"] {
+			for _, target := range targets {
+				if !trusts(principal, target) {
+					continue
+				}
+				if trusted[target] == nil {
+					trusted[target] = make(map[string]bool)
+				}
+				for _, value := range conditionIDs {
+					trusted[target][value] = true
+				}
+			}
+		}
+	}
+	for _, target := range targets {
+		name := target
+		if accountIDPattern.MatchString(target) {
+			name = "account " + target
+		}
+		for i, externalID := range trust.ExternalIDs {
+			if trusted[target][externalID] {
+				continue
+			}
+			if i > 0 {
+				return fmt.Errorf("trust policy does not let %s assume the role with the previous external ID", name)
+			}
+			return fmt.Errorf("trust policy does not let %s assume the role with the configured external ID", name)
+		}
+	}
+	return nil
+}
+
+func allowsAssumeRole(statement PolicyStatement) bool {
+	for _, action := range statement.Action {
+		switch action {
+		case "sts:AssumeRole", "sts:*", "*":
+			return true
+		}
+	}
+	return false
+}
+
+// isUptAccountRole reports whether principal is a role of the Uptycs account.
+func isUptAccountRole(principal string, uptAccountID string) bool {
+	return strings.HasPrefix(principal, "arn:aws:iam::"+uptAccountID+":role/")
+}
+
+// VerifyIntegrationTrust checks that the trust of the integration role lets
+// Uptycs in. The policy generated for the expected trust and the policy of
+// the role are always evaluated statically, and the role is assumed when a
+// verifier profile is set. It returns one of the verification statuses, and
+// the failed check for VerificationFailed.
+func VerifyIntegrationTrust(ctx context.Context, svc *iam.Client, v TrustVerification) (string, error) {
+	fail := func(err error) (string, error) {
+		return VerificationFailed, err
+	}
+	if err := checkTrustPolicy(v.Trust.policy().String(), v.Trust); err != nil {
+		return fail(&Error{Kind: ErrTrustMisconfigured, Step: StepVerifyTrust, Err: err})
+	}
+
+	roleOut, err := svc.GetRole(ctx, &iam.GetRoleInput{RoleName: &v.RoleName})
+	if err != nil {
+		return fail(newError(StepGetRole, err))
+	}
+	if roleOut.Role == nil || roleOut.Role.Arn == nil || roleOut.Role.AssumeRolePolicyDocument == nil {
+		return fail(newError(StepGetRole, fmt.Errorf("invalid roleOutput for %s", v.RoleName)))
+	}
+	if err := checkTrustPolicy(*roleOut.Role.AssumeRolePolicyDocument, v.Trust); err != nil {
+		return fail(&Error{Kind: ErrTrustMisconfigured, Step: StepVerifyTrust, Err: err})
+	}
+
+	if v.VerifierProfile == "" {
+		return VerificationPolicyValid, nil
+	}
+	if err := assumeAsVerifier(ctx, v.VerifierProfile, *roleOut.Role.Arn, v.Trust.ExternalIDs[0]); err != nil {
+		return fail(err)
+	}
+	return VerificationVerified, nil
+}
+
+// assumeAsVerifier assumes roleArn with the credentials of profileName, once
+// with the external ID, which must succeed, and once without, which must be
+// denied.
+func assumeAsVerifier(ctx context.Context, profileName string, roleArn string, externalID string) error {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion("aws-global"),
+		config.WithSharedConfigProfile(profileName),
+	)
+	if err != nil {
+		return newError(StepLoadConfig, err)
+	}
+	svc := sts.NewFromConfig(cfg)
+	input := &sts.AssumeRoleInput{
+		RoleArn:         &roleArn,
+		RoleSessionName: aws.String(verifySessionName),
+		DurationSeconds: aws.Int32(900),
+	}
+
+	// A new trust policy takes a few seconds to be honoured by STS.
+	for attempt := 0; ; attempt++ {
+		withID := *input
+		withID.ExternalId = &externalID
+		_, err = svc.AssumeRole(ctx, &withID)
+		if err == nil {
+			break
+		}
+		if !isAccessDenied(err) || attempt >= assumeRetries {
+			return &Error{Kind: ErrTrustVerificationFailed, Step: StepVerifyAssumeRole, Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return newError(StepVerifyAssumeRole, ctx.Err())
+		case <-time.After(assumeRetryWait):
+		}
+	}
+
+	_, err = svc.AssumeRole(ctx, input)
+	if err == nil {
+		return &Error{
+			Kind: ErrTrustVerificationFailed,
+			Step: StepVerifyAssumeRole,
+			Err:  fmt.Errorf("role %s can be assumed without the external ID", roleArn),
+		}
+	}
+	if !isAccessDenied(err) {
+		return newError(StepVerifyAssumeRole, err)
+	}
+	return nil
+}
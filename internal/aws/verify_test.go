@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckTrustPolicy(t *testing.T) {
+	const uptAccountID = "123456789013"
+	const externalID = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+	cases := []struct {
+		name       string
+		document   string
+		uptAccount string
+		externalID string
+		err        string
+	}{
+		{"generated", uptycsTrustPolicy(uptAccountID, externalID), uptAccountID, externalID, ""},
+		{"url encoded", "%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Effect%22%3A%22Allow%22%2C%22Principal%22%3A%7B%22AWS%22%3A%22arn%3Aaws%3Aiam%3A%3A123456789013%3Aroot%22%7D%2C%22Action%22%3A%22sts%3AAssumeRole%22%2C%22Condition%22%3A%7B%22StringEquals%22%3A%7B%22sts%3AExternalId%22%3A%226a9375c1-47c0-470c-9217-d2f9d2d185f1%22%7D%7D%7D%5D%7D", uptAccountID, externalID, ""},
+		{"short account", uptycsTrustPolicy("12345", externalID), "12345", externalID, "12 digit"},
+		{"quote in external ID", uptycsTrustPolicy(uptAccountID, `a"b`), uptAccountID, `a"b`, "external_id"},
+		{"other external ID", uptycsTrustPolicy(uptAccountID, "other-id"), uptAccountID, externalID, "configured external ID"},
+		{"other account", uptycsTrustPolicy("999999999999", externalID), uptAccountID, externalID, "configured external ID"},
+		{"no external ID", `{"Version":"2012-10-17","Statement":[
+			{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789013:root"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"` + externalID + `"}}},
+			{"Effect":"Allow","Principal":{"AWS":"*"},"Action":"sts:AssumeRole"}]}`, uptAccountID, externalID, "without an external ID"},
+		{"role principal", `{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::123456789013:role/Uptycs"},"Action":"sts:AssumeRole","Condition":{"StringEquals":{"sts:ExternalId":"` + externalID + `"}}}}`, uptAccountID, externalID, ""},
+	}
+	for _, c := range cases {
+		err := checkTrustPolicy(c.document, Trust{UptAccountIDs: []string{c.uptAccount}, ExternalIDs: []string{c.externalID}})
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestCheckTrustPolicyRotation(t *testing.T) {
+	const uptAccountID = "123456789013"
+	const current, previous = "6a9375c1-47c0-470c-9217-d2f9d2d185f1", "0f1e2d3c-4b5a-4697-8887-a9b8c7d6e5f4"
+
+	rotating := uptycsTrustPolicy(uptAccountID, current, previous)
+	if err := checkTrustPolicy(rotating, Trust{UptAccountIDs: []string{uptAccountID}, ExternalIDs: []string{current, previous}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	policy, err := ParsePolicyDocument(rotating)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := policy.conditionValues("sts:ExternalId"); len(ids) != 2 {
+		t.Errorf("expected both external IDs in the trust policy, got %v", ids)
+	}
+
+	err = checkTrustPolicy(uptycsTrustPolicy(uptAccountID, current), Trust{UptAccountIDs: []string{uptAccountID}, ExternalIDs: []string{current, previous}})
+	if err == nil || !strings.Contains(err.Error(), "previous external ID") {
+		t.Errorf("expected the previous external ID to be missing, got %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"github.com/uptycslabs/terraform-provider-uptycscspm/internal/uptycs"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = awsIntegrationResourceType{}
+var _ tfsdk.Resource = awsIntegrationResource{}
+var _ tfsdk.ResourceWithImportState = awsIntegrationResource{}
+
+type awsIntegrationResourceType struct{}
+
+func (t awsIntegrationResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Registration of an AWS account with the Uptycs tenant, through the integration role of the account. " +
+			"Requires the Uptycs API settings of the provider",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "ID of the registration in the tenant",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()},
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{tfsdk.RequiresReplace()},
+			},
+			"name": {
+				MarkdownDescription: "Name of the account in the Uptycs console",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"role_arn": {
+				MarkdownDescription: "ARN of the integration role assumed by Uptycs, such as the `role` of `uptycscspm_role`",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"external_id": {
+				MarkdownDescription: "External ID required by the trust policy of the role",
+				Required:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+			"log_sources": {
+				MarkdownDescription: "Buckets Uptycs reads the CloudTrail logs of the account from",
+				Optional:            true,
+				Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+					"bucket_name": {
+						MarkdownDescription: "Cloudtrail Bucket",
+						Required:            true,
+						Type:                types.StringType,
+					},
+					"bucket_region": {
+						MarkdownDescription: "Cloudtrail Bucket Region",
+						Required:            true,
+						Type:                types.StringType,
+					},
+					"prefix": {
+						MarkdownDescription: "Key prefix of the logs, such as the `prefix` of `uptycscspm_org_trail`",
+						Optional:            true,
+						Type:                types.StringType,
+					},
+					"queue_url": {
+						MarkdownDescription: "URL of the queue notifying of new logs, such as the `queue_url` of `uptycscspm_log_notifications`",
+						Optional:            true,
+						Type:                types.StringType,
+					},
+				}),
+			},
+			"status": {
+				MarkdownDescription: "Status of the account reported by the tenant",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t awsIntegrationResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return awsIntegrationResource{
+		provider: provider,
+	}, diags
+}
+
+type awsIntegrationResourceData struct {
+	ID         types.String                  `tfsdk:"id"`
+	AccountID  types.String                  `tfsdk:"account_id"`
+	Name       types.String                  `tfsdk:"name"`
+	RoleArn    types.String                  `tfsdk:"role_arn"`
+	ExternalID types.String                  `tfsdk:"external_id"`
+	LogSources []awsIntegrationLogSourceData `tfsdk:"log_sources"`
+	Status     types.String                  `tfsdk:"status"`
+	Timeouts   []timeoutsData                `tfsdk:"timeouts"`
+}
+
+type awsIntegrationLogSourceData struct {
+	BucketName   types.String `tfsdk:"bucket_name"`
+	BucketRegion types.String `tfsdk:"bucket_region"`
+	Prefix       types.String `tfsdk:"prefix"`
+	QueueURL     types.String `tfsdk:"queue_url"`
+}
+
+// account returns the registration described by d.
+func (d awsIntegrationResourceData) account() uptycs.AwsAccount {
+	account := uptycs.AwsAccount{
+		ID:        d.ID.Value,
+		AccountID: d.AccountID.Value,
+		Name:      d.Name.Value,
+		AccessConfig: uptycs.AwsAccessConfig{
+			RoleArn:    d.RoleArn.Value,
+			ExternalID: d.ExternalID.Value,
+		},
+		LogSources: []uptycs.AwsLogSource{},
+	}
+	for _, source := range d.LogSources {
+		account.LogSources = append(account.LogSources, uptycs.AwsLogSource{
+			Type:         uptycs.LogSourceCloudTrail,
+			BucketName:   source.BucketName.Value,
+			BucketRegion: source.BucketRegion.Value,
+			BucketPrefix: source.Prefix.Value,
+			QueueURL:     source.QueueURL.Value,
+		})
+	}
+	return account
+}
+
+// setAccount sets d to what the tenant reports for the registration, so
+// that changes made in the Uptycs console show up as drift. Optional values
+// the tenant reports empty stay null when they were not set.
+func (d *awsIntegrationResourceData) setAccount(account *uptycs.AwsAccount) {
+	d.ID = types.String{Value: account.ID}
+	d.AccountID = types.String{Value: account.AccountID}
+	d.Name = types.String{Value: account.Name}
+	d.RoleArn = types.String{Value: account.AccessConfig.RoleArn}
+	d.ExternalID = types.String{Value: account.AccessConfig.ExternalID}
+	d.Status = types.String{Value: account.Status}
+
+	var sources []awsIntegrationLogSourceData
+	for i, source := range account.LogSources {
+		// Without a source at the same position, as on import, nothing
+		// was configured.
+		prev := awsIntegrationLogSourceData{Prefix: types.String{Null: true}, QueueURL: types.String{Null: true}}
+		if i < len(d.LogSources) {
+			prev = d.LogSources[i]
+		}
+		sources = append(sources, awsIntegrationLogSourceData{
+			BucketName:   types.String{Value: source.BucketName},
+			BucketRegion: types.String{Value: source.BucketRegion},
+			Prefix:       optionalString(source.BucketPrefix, prev.Prefix),
+			QueueURL:     optionalString(source.QueueURL, prev.QueueURL),
+		})
+	}
+	d.LogSources = sources
+}
+
+// optionalString returns value, or null when value is empty and prev was
+// not set, or is not known yet.
+func optionalString(value string, prev types.String) types.String {
+	if value == "" && (prev.Null || prev.Unknown) {
+		return types.String{Null: true}
+	}
+	return types.String{Value: value}
+}
+
+type awsIntegrationResource struct {
+	provider provider
+}
+
+func (r awsIntegrationResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data awsIntegrationResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.uptycsClient(&resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	account, err := client.CreateAwsAccount(ctx, data.account())
+	if uptycs.IsKind(err, uptycs.ErrConflict) {
+		if existing, findErr := client.FindAwsAccount(ctx, data.AccountID.Value); findErr == nil && existing != nil {
+			resp.Diagnostics.AddError("Uptycs Object Already Exists",
+				fmt.Sprintf("Account %s is already registered with the Uptycs tenant as %s. "+
+					"Import it with `terraform import` instead.", data.AccountID.Value, existing.ID))
+			return
+		}
+	}
+	if err != nil {
+		addUptycsError(&resp.Diagnostics, fmt.Sprintf("Unable to register account %s with Uptycs", data.AccountID.Value), err)
+		return
+	}
+	data.setAccount(account)
+
+	tflog.Trace(ctx, "registered an AWS account with Uptycs")
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r awsIntegrationResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data awsIntegrationResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.uptycsClient(&resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	account, err := client.GetAwsAccount(ctx, data.ID.Value)
+	if uptycs.IsKind(err, uptycs.ErrNotFound) {
+		// The registration was removed in the Uptycs console, plan to
+		// register the account again.
+		tflog.Warn(ctx, "Uptycs registration not found, removing from state", map[string]interface{}{
+			"id":         data.ID.Value,
+			"account_id": data.AccountID.Value,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addUptycsError(&resp.Diagnostics, fmt.Sprintf("Unable to get Uptycs registration %s", data.ID.Value), err)
+		return
+	}
+	data.setAccount(account)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r awsIntegrationResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data awsIntegrationResourceData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.uptycsClient(&resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	account, err := client.UpdateAwsAccount(ctx, data.account())
+	if err != nil {
+		addUptycsError(&resp.Diagnostics, fmt.Sprintf("Unable to update Uptycs registration of account %s", data.AccountID.Value), err)
+		return
+	}
+	data.setAccount(account)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r awsIntegrationResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data awsIntegrationResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.uptycsClient(&resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	if err := client.DeleteAwsAccount(ctx, data.ID.Value); err != nil {
+		addUptycsError(&resp.Diagnostics, fmt.Sprintf("Unable to remove Uptycs registration of account %s", data.AccountID.Value), err)
+		return
+	}
+}
+
+func (r awsIntegrationResource) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	tfsdk.ResourceImportStatePassthroughID(ctx, tftypes.NewAttributePath().WithAttributeName("id"), req, resp)
+}
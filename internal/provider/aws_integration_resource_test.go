@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/uptycslabs/terraform-provider-uptycscspm/internal/uptycs"
+)
+
+func TestAccAwsIntegrationResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccAwsIntegrationResourceConfig("123456789012"),
+				// Expect to fail as the provider has no Uptycs API key
+				ExpectError: regexp.MustCompile("Uptycs API Not Configured"),
+			},
+		},
+	})
+}
+
+func TestAwsIntegrationSetAccount(t *testing.T) {
+	account := &uptycs.AwsAccount{
+		ID:        "cloud-account-1",
+		AccountID: "123456789012",
+		LogSources: []uptycs.AwsLogSource{
+			{Type: uptycs.LogSourceCloudTrail, BucketName: "logs", BucketRegion: "us-east-1"},
+			{Type: uptycs.LogSourceCloudTrail, BucketName: "other", BucketRegion: "us-east-1", BucketPrefix: "AWSLogs/"},
+		},
+	}
+	for _, c := range []struct {
+		name   string
+		prior  []awsIntegrationLogSourceData
+		prefix []types.String
+	}{
+		{
+			name:   "import",
+			prefix: []types.String{{Null: true}, {Value: "AWSLogs/"}},
+		},
+		{
+			name: "not configured",
+			prior: []awsIntegrationLogSourceData{
+				{Prefix: types.String{Null: true}, QueueURL: types.String{Null: true}},
+				{Prefix: types.String{Null: true}, QueueURL: types.String{Null: true}},
+			},
+			prefix: []types.String{{Null: true}, {Value: "AWSLogs/"}},
+		},
+		{
+			name: "configured empty",
+			prior: []awsIntegrationLogSourceData{
+				{Prefix: types.String{Value: ""}, QueueURL: types.String{Null: true}},
+			},
+			prefix: []types.String{{Value: ""}, {Value: "AWSLogs/"}},
+		},
+		{
+			name: "removed in the console",
+			prior: []awsIntegrationLogSourceData{
+				{Prefix: types.String{Value: "CloudTrail/"}, QueueURL: types.String{Null: true}},
+			},
+			prefix: []types.String{{Value: ""}, {Value: "AWSLogs/"}},
+		},
+	} {
+		data := awsIntegrationResourceData{LogSources: c.prior}
+		data.setAccount(account)
+		if len(data.LogSources) != len(c.prefix) {
+			t.Fatalf("%s: unexpected log sources %+v", c.name, data.LogSources)
+		}
+		for i, source := range data.LogSources {
+			if source.Prefix != c.prefix[i] {
+				t.Errorf("%s: expected prefix %+v of source %d, got %+v", c.name, c.prefix[i], i, source.Prefix)
+			}
+			if !source.QueueURL.Null {
+				t.Errorf("%s: expected a null queue URL of source %d, got %+v", c.name, i, source.QueueURL)
+			}
+		}
+	}
+}
+
+func testAccAwsIntegrationResourceConfig(accountID string) string {
+	return fmt.Sprintf(`
+resource "uptycscspm_aws_integration" "test" {
+  account_id = %[1]q
+  name = "workload"
+  role_arn = "arn:aws:iam::%[1]s:role/UptycsIntegration"
+  external_id = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  log_sources = [{
+    bucket_name = "cloudtrail-logs"
+    bucket_region = "us-east-1"
+  }]
+}
+`, accountID)
+}
@@ -0,0 +1,271 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = bucketPolicyGrantResourceType{}
+var _ tfsdk.Resource = bucketPolicyGrantResource{}
+
+type bucketPolicyGrantResourceType struct{}
+
+func (t bucketPolicyGrantResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	replaced := []tfsdk.AttributePlanModifier{tfsdk.RequiresReplace()}
+	return tfsdk.Schema{
+		MarkdownDescription: "Statement of the policy of a CloudTrail bucket letting an Uptycs integration role of another account read the logs. " +
+			"The other statements of the bucket policy are kept as they are",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Bucket name and statement ID, separated by `/`",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()},
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID of the bucket owner, for example the log archive account",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"bucket_name": {
+				MarkdownDescription: "Cloudtrail Bucket",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"bucket_region": {
+				MarkdownDescription: "Cloudtrail Bucket Region",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"role_arn": {
+				MarkdownDescription: "ARN of the integration role allowed to read the logs, for example the `role` of `uptycscspm_role`. " +
+					"Changing it updates the statement in place, without changing its `sid`",
+				Required: true,
+				Type:     types.StringType,
+			},
+			"prefix": {
+				MarkdownDescription: "Key prefix of the logs the role may read. Defaults to the whole bucket",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"sid": {
+				MarkdownDescription: "Statement ID tagging the grant in the bucket policy. Defaults to `UptycsCspmRead` followed by the account ID and the name of the role. " +
+					"The default is chosen when the grant is created and kept when `role_arn` changes later, so the ID may then name another role. " +
+					"Creating the grant fails when the bucket policy already has a statement with this ID",
+				Optional:      true,
+				Computed:      true,
+				Type:          types.StringType,
+				PlanModifiers: []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown(), tfsdk.RequiresReplace()},
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t bucketPolicyGrantResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return bucketPolicyGrantResource{
+		provider: provider,
+	}, diags
+}
+
+type bucketPolicyGrantResourceData struct {
+	ID                   types.String   `tfsdk:"id"`
+	ProfileName          types.String   `tfsdk:"profile_name"`
+	AccountID            types.String   `tfsdk:"account_id"`
+	OrgAccessRoleName    types.String   `tfsdk:"org_access_role_name"`
+	UseCallerCredentials types.Bool     `tfsdk:"use_caller_credentials"`
+	BucketName           types.String   `tfsdk:"bucket_name"`
+	BucketRegion         types.String   `tfsdk:"bucket_region"`
+	RoleArn              types.String   `tfsdk:"role_arn"`
+	Prefix               types.String   `tfsdk:"prefix"`
+	Sid                  types.String   `tfsdk:"sid"`
+	Timeouts             []timeoutsData `tfsdk:"timeouts"`
+}
+
+func (d bucketPolicyGrantResourceData) grant() awsinternal.BucketGrant {
+	return awsinternal.BucketGrant{
+		Sid:        d.Sid.Value,
+		BucketName: d.BucketName.Value,
+		RoleArn:    d.RoleArn.Value,
+		Prefix:     d.Prefix.Value,
+	}
+}
+
+// setDefaultSid derives the sid from the role when it is not set. It is
+// only called on create, so the sid stays the same when the role changes.
+func (d *bucketPolicyGrantResourceData) setDefaultSid() {
+	if d.Sid.Null || d.Sid.Unknown || d.Sid.Value == "" {
+		d.Sid = types.String{Value: awsinternal.BucketGrantSid(d.RoleArn.Value)}
+	}
+}
+
+type bucketPolicyGrantResource struct {
+	provider provider
+}
+
+// put merges the grant of data into the bucket policy. A new grant fails on
+// a statement with the same sid, an existing one replaces it.
+func (r bucketPolicyGrantResource) put(ctx context.Context, data bucketPolicyGrantResourceData, replace bool, diags *diag.Diagnostics) {
+	svc, err := awsinternal.GetAwsS3Client(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		addAwsError(diags, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), err)
+		return
+	}
+	put := awsinternal.AddBucketGrant
+	if replace {
+		put = awsinternal.PutBucketGrant
+	}
+	if err := put(ctx, svc, data.grant()); err != nil {
+		addAwsError(diags, fmt.Sprintf("Unable to grant %s read access to bucket %s", data.RoleArn.Value, data.BucketName.Value), err)
+	}
+}
+
+func (r bucketPolicyGrantResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data bucketPolicyGrantResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	data.setDefaultSid()
+	r.put(ctx, data, false, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ID = types.String{Value: data.BucketName.Value + "/" + data.Sid.Value}
+
+	tflog.Trace(ctx, "created a bucket policy grant")
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r bucketPolicyGrantResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data bucketPolicyGrantResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	svc, err := awsinternal.GetAwsS3Client(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), err)
+		return
+	}
+	grant, err := awsinternal.GetBucketGrant(ctx, svc, data.BucketName.Value, data.Sid.Value)
+	if awsinternal.IsKind(err, awsinternal.ErrBucketNotFound) || (err == nil && grant == nil) {
+		// The statement was removed outside of Terraform, plan to add it again.
+		tflog.Warn(ctx, "bucket policy grant not found, removing from state", map[string]interface{}{
+			"bucket_name": data.BucketName.Value,
+			"sid":         data.Sid.Value,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get policy of bucket %s", data.BucketName.Value), err)
+		return
+	}
+	// S3 replaces the principal of a deleted role by its unique ID, which
+	// shows up here as a change of role_arn.
+	data.RoleArn = types.String{Value: grant.RoleArn}
+	if !data.Prefix.Null || grant.Prefix != "" {
+		data.Prefix = types.String{Value: grant.Prefix}
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r bucketPolicyGrantResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data bucketPolicyGrantResourceData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	// The statement with the same sid is replaced in place.
+	r.put(ctx, data, true, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r bucketPolicyGrantResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data bucketPolicyGrantResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	svc, err := awsinternal.GetAwsS3Client(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), err)
+		return
+	}
+	if err := awsinternal.DeleteBucketGrant(ctx, svc, data.BucketName.Value, data.Sid.Value); err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to remove statement %s from policy of bucket %s", data.Sid.Value, data.BucketName.Value), err)
+		return
+	}
+}
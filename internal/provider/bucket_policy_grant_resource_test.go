@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestBucketPolicyGrantDefaultSid(t *testing.T) {
+	data := bucketPolicyGrantResourceData{
+		BucketName: types.String{Value: "example-log-archive"},
+		RoleArn:    types.String{Value: "arn:aws:iam::123456789012:role/uptcloud"},
+		Prefix:     types.String{Value: "AWSLogs/"},
+		Sid:        types.String{Null: true},
+	}
+	data.setDefaultSid()
+	if data.Sid.Value != "UptycsCspmRead123456789012uptcloud" {
+		t.Fatalf("unexpected default sid %s", data.Sid.Value)
+	}
+	expected := awsinternal.BucketGrant{
+		Sid:        "UptycsCspmRead123456789012uptcloud",
+		BucketName: "example-log-archive",
+		RoleArn:    "arn:aws:iam::123456789012:role/uptcloud",
+		Prefix:     "AWSLogs/",
+	}
+	if grant := data.grant(); grant != expected {
+		t.Errorf("expected %+v, got %+v", expected, grant)
+	}
+
+	// The sid chosen on create is kept when the role changes.
+	data.RoleArn = types.String{Value: "arn:aws:iam::234567890123:role/uptcloud"}
+	data.setDefaultSid()
+	if data.Sid.Value != "UptycsCspmRead123456789012uptcloud" {
+		t.Errorf("expected the sid to be kept, got %s", data.Sid.Value)
+	}
+
+	configured := bucketPolicyGrantResourceData{RoleArn: data.RoleArn, Sid: types.String{Value: "LogArchiveRead"}}
+	configured.setDefaultSid()
+	if configured.Sid.Value != "LogArchiveRead" {
+		t.Errorf("expected the configured sid, got %s", configured.Sid.Value)
+	}
+}
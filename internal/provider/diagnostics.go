@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+	"github.com/uptycslabs/terraform-provider-uptycscspm/internal/uptycs"
+)
+
+// addAwsError appends an error diagnostic for err. Errors classified by the
+// internal/aws package are reported with their own summary, the step that
+// failed and a remediation hint.
+func addAwsError(diags *diag.Diagnostics, action string, err error) {
+	diags.AddError(awsErrorDiagnostic(action, err))
+}
+
+// addAwsWarning is addAwsError for failures that do not fail the operation.
+func addAwsWarning(diags *diag.Diagnostics, action string, err error) {
+	diags.AddWarning(awsErrorDiagnostic(action, err))
+}
+
+func awsErrorDiagnostic(action string, err error) (string, string) {
+	awsErr, ok := awsinternal.AsError(err)
+	if !ok {
+		return "Client Error", fmt.Sprintf("%s. err=%s", action, err)
+	}
+	detail := fmt.Sprintf("%s. err=%s\n\nFailed step: %s\n\n%s", action, err, awsErr.Step, awsErr.Remediation())
+	if awsErr.CleanupErr != nil {
+		detail += fmt.Sprintf("\n\nResources created before the failure could not all be removed: %s", awsErr.CleanupErr)
+	}
+	return awsErr.Summary(), detail
+}
+
+// awsErrorMessage is a one line description of err, used where errors are
+// recorded in state rather than reported as diagnostics.
+func awsErrorMessage(err error) string {
+	awsErr, ok := awsinternal.AsError(err)
+	if !ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s during %s: %s", awsErr.Summary(), awsErr.Step, err)
+}
+
+// addUptycsError appends an error diagnostic for a failed call to the Uptycs
+// API, with the summary and remediation hint of its classification.
+func addUptycsError(diags *diag.Diagnostics, action string, err error) {
+	uptErr, ok := uptycs.AsError(err)
+	if !ok {
+		diags.AddError("Client Error", fmt.Sprintf("%s. err=%s", action, err))
+		return
+	}
+	diags.AddError(uptErr.Summary(), fmt.Sprintf("%s. err=%s\n\n%s", action, err, uptErr.Remediation()))
+}
@@ -0,0 +1,331 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = eventForwardingResourceType{}
+var _ tfsdk.Resource = eventForwardingResource{}
+
+// Default names of the rules and of the role of uptycscspm_event_forwarding.
+const (
+	defaultForwardingRuleName = "UptycsCspmEventForwarding"
+	defaultForwardingRoleName = "UptycsCspmEventForwardingRole"
+)
+
+type eventForwardingResourceType struct{}
+
+func (t eventForwardingResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	replaced := []tfsdk.AttributePlanModifier{tfsdk.RequiresReplace()}
+	defaulted := []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown(), tfsdk.RequiresReplace()}
+	return tfsdk.Schema{
+		MarkdownDescription: "EventBridge rules forwarding the CloudTrail management events of an account to the Uptycs event bus, " +
+			"and the IAM role they use to put the events",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Account ID and rule name, separated by `/`",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()},
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"regions": {
+				MarkdownDescription: "Regions in which a rule forwards the management events",
+				Required:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"event_bus_arn": {
+				MarkdownDescription: "ARN of the Uptycs event bus",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"rule_name": {
+				MarkdownDescription: fmt.Sprintf("Name of the rules. Defaults to `%s`. Creating the resource fails when a rule already exists", defaultForwardingRuleName),
+				Optional:            true,
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       defaulted,
+			},
+			"role_name": {
+				MarkdownDescription: fmt.Sprintf("Name of the IAM role EventBridge assumes to put the events. Defaults to `%s`. "+
+					"Creating the resource fails when the role already exists", defaultForwardingRoleName),
+				Optional:      true,
+				Computed:      true,
+				Type:          types.StringType,
+				PlanModifiers: defaulted,
+			},
+			"role_arn": {
+				MarkdownDescription: "Role ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()},
+			},
+			"rule_arns": {
+				MarkdownDescription: "Rule ARNs, in the order of `regions`",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t eventForwardingResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return eventForwardingResource{
+		provider: provider,
+	}, diags
+}
+
+type eventForwardingResourceData struct {
+	ID                   types.String   `tfsdk:"id"`
+	ProfileName          types.String   `tfsdk:"profile_name"`
+	AccountID            types.String   `tfsdk:"account_id"`
+	OrgAccessRoleName    types.String   `tfsdk:"org_access_role_name"`
+	UseCallerCredentials types.Bool     `tfsdk:"use_caller_credentials"`
+	Regions              []string       `tfsdk:"regions"`
+	EventBusArn          types.String   `tfsdk:"event_bus_arn"`
+	RuleName             types.String   `tfsdk:"rule_name"`
+	RoleName             types.String   `tfsdk:"role_name"`
+	RoleArn              types.String   `tfsdk:"role_arn"`
+	RuleArns             types.List     `tfsdk:"rule_arns"`
+	Timeouts             []timeoutsData `tfsdk:"timeouts"`
+}
+
+func (d eventForwardingResourceData) spec() awsinternal.EventForwardingSpec {
+	return awsinternal.EventForwardingSpec{
+		ProfileName:          d.ProfileName.Value,
+		AccountID:            d.AccountID.Value,
+		RoleToAssume:         d.OrgAccessRoleName.Value,
+		UseCallerCredentials: d.UseCallerCredentials.Value,
+		Regions:              d.Regions,
+		EventBusArn:          d.EventBusArn.Value,
+		RuleName:             d.RuleName.Value,
+		RoleName:             d.RoleName.Value,
+	}
+}
+
+type eventForwardingResource struct {
+	provider provider
+}
+
+func (r eventForwardingResource) iamClient(ctx context.Context, data eventForwardingResourceData, diags *diag.Diagnostics) *iam.Client {
+	svc, err := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		addAwsError(diags, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), err)
+		return nil
+	}
+	return svc
+}
+
+func (r eventForwardingResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data eventForwardingResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	if data.RuleName.Null || data.RuleName.Value == "" {
+		data.RuleName = types.String{Value: defaultForwardingRuleName}
+	}
+	if data.RoleName.Null || data.RoleName.Value == "" {
+		data.RoleName = types.String{Value: defaultForwardingRoleName}
+	}
+	svc := r.iamClient(ctx, data, &resp.Diagnostics)
+	if svc == nil {
+		return
+	}
+	roleArn, ruleArns, err := awsinternal.CreateEventForwarding(ctx, svc, data.spec())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to create event forwarding in account %s", data.AccountID.Value), err)
+		return
+	}
+	data.ID = types.String{Value: data.AccountID.Value + "/" + data.RuleName.Value}
+	data.RoleArn = types.String{Value: roleArn}
+	data.RuleArns = stringList(ruleArns)
+
+	tflog.Trace(ctx, "created event forwarding")
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r eventForwardingResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data eventForwardingResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	svc := r.iamClient(ctx, data, &resp.Diagnostics)
+	if svc == nil {
+		return
+	}
+	_, err := awsinternal.GetIntegrationRoleName(ctx, svc, data.RoleName.Value)
+	if awsinternal.IsKind(err, awsinternal.ErrNoSuchEntity) {
+		// The role was deleted outside of Terraform, plan to re-create it.
+		tflog.Warn(ctx, "event forwarding role not found, removing from state", map[string]interface{}{
+			"account_id": data.AccountID.Value,
+			"role_name":  data.RoleName.Value,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		addAwsError(&resp.Diagnostics, "Unable to get event forwarding role", err)
+		return
+	}
+
+	// Regions whose rule was deleted outside of Terraform are dropped, so
+	// that the plan adds them again.
+	var regions []string
+	var ruleArns []string
+	for i, region := range data.Regions {
+		exists, err := awsinternal.ForwardingRuleExists(ctx, data.spec(), region)
+		if err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get event forwarding rule in %s", region), err)
+			return
+		}
+		if !exists {
+			continue
+		}
+		regions = append(regions, region)
+		if i < len(data.RuleArns.Elems) {
+			ruleArns = append(ruleArns, data.RuleArns.Elems[i].(types.String).Value)
+		}
+	}
+	data.Regions = regions
+	data.RuleArns = stringList(ruleArns)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r eventForwardingResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data eventForwardingResourceData
+	var prior eventForwardingResourceData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &prior)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	svc := r.iamClient(ctx, data, &resp.Diagnostics)
+	if svc == nil {
+		return
+	}
+	roleArn, _, err := awsinternal.PutForwardingRole(ctx, svc, data.spec())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to update event forwarding role in account %s", data.AccountID.Value), err)
+		return
+	}
+	data.RoleArn = types.String{Value: roleArn}
+
+	var ruleArns []string
+	for _, region := range data.Regions {
+		ruleArn, err := awsinternal.PutForwardingRule(ctx, data.spec(), region, roleArn)
+		if err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to update event forwarding rule in %s", region), err)
+			return
+		}
+		ruleArns = append(ruleArns, ruleArn)
+	}
+	data.RuleArns = stringList(ruleArns)
+	for _, region := range prior.Regions {
+		if containsString(data.Regions, region) {
+			continue
+		}
+		if err := awsinternal.DeleteForwardingRule(ctx, data.spec(), region); err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to delete event forwarding rule in %s", region), err)
+			return
+		}
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r eventForwardingResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data eventForwardingResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	for _, region := range data.Regions {
+		if err := awsinternal.DeleteForwardingRule(ctx, data.spec(), region); err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to delete event forwarding rule in %s", region), err)
+			return
+		}
+	}
+	svc := r.iamClient(ctx, data, &resp.Diagnostics)
+	if svc == nil {
+		return
+	}
+	if err := awsinternal.DeleteForwardingRole(ctx, svc, data.RoleName.Value); err != nil {
+		addAwsError(&resp.Diagnostics, "Unable to delete event forwarding role", err)
+		return
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestEventForwardingSpec(t *testing.T) {
+	data := eventForwardingResourceData{
+		ProfileName:          types.String{Value: "workload"},
+		AccountID:            types.String{Value: "123456789012"},
+		OrgAccessRoleName:    types.String{Value: "OrganizationAccountAccessRole"},
+		UseCallerCredentials: types.Bool{Null: true},
+		Regions:              []string{"us-east-1", "eu-west-1"},
+		EventBusArn:          types.String{Value: "arn:aws:events:us-east-1:012345678912:event-bus/uptycs"},
+		RuleName:             types.String{Value: defaultForwardingRuleName},
+		RoleName:             types.String{Value: defaultForwardingRoleName},
+	}
+	expected := awsinternal.EventForwardingSpec{
+		ProfileName:  "workload",
+		AccountID:    "123456789012",
+		RoleToAssume: "OrganizationAccountAccessRole",
+		Regions:      []string{"us-east-1", "eu-west-1"},
+		EventBusArn:  "arn:aws:events:us-east-1:012345678912:event-bus/uptycs",
+		RuleName:     defaultForwardingRuleName,
+		RoleName:     defaultForwardingRoleName,
+	}
+	if spec := data.spec(); !reflect.DeepEqual(spec, expected) {
+		t.Errorf("expected %+v, got %+v", expected, spec)
+	}
+}
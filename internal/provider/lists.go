@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// stringList converts values to a list attribute value.
+func stringList(values []string) types.List {
+	list := types.List{ElemType: types.StringType, Elems: []attr.Value{}}
+	for _, value := range values {
+		list.Elems = append(list.Elems, types.String{Value: value})
+	}
+	return list
+}
+
+// listStrings returns the values of a list attribute value, and whether the
+// list and all of its values are known. A null list has no values.
+func listStrings(list types.List) ([]string, bool) {
+	if list.Unknown {
+		return nil, false
+	}
+	values := make([]string, 0, len(list.Elems))
+	for _, elem := range list.Elems {
+		value, ok := elem.(types.String)
+		if !ok || value.Unknown {
+			return nil, false
+		}
+		values = append(values, value.Value)
+	}
+	return values, true
+}
@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = logNotificationsResourceType{}
+var _ tfsdk.Resource = logNotificationsResource{}
+
+type logNotificationsResourceType struct{}
+
+func (t logNotificationsResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	replaced := []tfsdk.AttributePlanModifier{tfsdk.RequiresReplace()}
+	computed := []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()}
+	return tfsdk.Schema{
+		MarkdownDescription: "Notifications of new CloudTrail logs, published by the log bucket to an SNS topic and delivered to an SQS queue " +
+			"consumed by Uptycs. The other notification settings of the bucket are kept as they are",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Queue ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID of the bucket owner",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"bucket_name": {
+				MarkdownDescription: "Cloudtrail Bucket",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"bucket_region": {
+				MarkdownDescription: "Cloudtrail Bucket Region, where the topic and the queue are created",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"name": {
+				MarkdownDescription: "Name of the topic and of the queue, which must not exist yet. SQS does not allow the name of a " +
+					"deleted queue to be used again for 60 seconds, so when the resource is replaced the queue cannot be created until " +
+					"then; apply again after a minute",
+				Required:      true,
+				Type:          types.StringType,
+				PlanModifiers: replaced,
+			},
+			"prefix": {
+				MarkdownDescription: "Notify only of the logs with this key prefix, such as the `prefix` of `uptycscspm_org_trail`",
+				Optional:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"role_arn": {
+				MarkdownDescription: "ARN of the integration role allowed by the queue policy to consume the queue. The role also needs " +
+					"the permissions added by `log_queue_arns` of `uptycscspm_policy_document`",
+				Optional: true,
+				Type:     types.StringType,
+			},
+			"topic_arn": {
+				MarkdownDescription: "Topic ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"queue_arn": {
+				MarkdownDescription: "Queue ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"queue_url": {
+				MarkdownDescription: "Queue URL",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"drift": {
+				MarkdownDescription: "Parts of the notifications removed outside of Terraform. A missing topic configuration of the bucket " +
+					"is added again in place, the topic and the queue are re-created when only one of them is missing",
+				Computed:      true,
+				Type:          types.StringType,
+				PlanModifiers: []tfsdk.AttributePlanModifier{driftModifier{inPlace: []string{logNotificationsMissingConfiguration}}},
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t logNotificationsResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return logNotificationsResource{
+		provider: provider,
+	}, diags
+}
+
+type logNotificationsResourceData struct {
+	ID                   types.String   `tfsdk:"id"`
+	ProfileName          types.String   `tfsdk:"profile_name"`
+	AccountID            types.String   `tfsdk:"account_id"`
+	OrgAccessRoleName    types.String   `tfsdk:"org_access_role_name"`
+	UseCallerCredentials types.Bool     `tfsdk:"use_caller_credentials"`
+	BucketName           types.String   `tfsdk:"bucket_name"`
+	BucketRegion         types.String   `tfsdk:"bucket_region"`
+	Name                 types.String   `tfsdk:"name"`
+	Prefix               types.String   `tfsdk:"prefix"`
+	RoleArn              types.String   `tfsdk:"role_arn"`
+	TopicArn             types.String   `tfsdk:"topic_arn"`
+	QueueArn             types.String   `tfsdk:"queue_arn"`
+	QueueURL             types.String   `tfsdk:"queue_url"`
+	Drift                types.String   `tfsdk:"drift"`
+	Timeouts             []timeoutsData `tfsdk:"timeouts"`
+}
+
+func (d logNotificationsResourceData) spec() awsinternal.LogNotificationSpec {
+	return awsinternal.LogNotificationSpec{
+		Name:         d.Name.Value,
+		AccountID:    d.AccountID.Value,
+		BucketName:   d.BucketName.Value,
+		BucketRegion: d.BucketRegion.Value,
+		Prefix:       d.Prefix.Value,
+		RoleArn:      d.RoleArn.Value,
+	}
+}
+
+// logNotificationsMissingConfiguration is the drift of log notifications
+// whose topic configuration was removed from the bucket, which Update adds
+// again.
+const logNotificationsMissingConfiguration = "missing topic configuration"
+
+// logNotificationsDrift describes the parts of existing log notifications
+// that are missing.
+func logNotificationsDrift(status awsinternal.LogNotificationStatus) string {
+	switch {
+	case !status.Topic:
+		return "missing topic"
+	case !status.Queue:
+		return "missing queue"
+	case !status.TopicConfiguration:
+		return logNotificationsMissingConfiguration
+	}
+	return ""
+}
+
+type logNotificationsResource struct {
+	provider provider
+}
+
+func (r logNotificationsResource) clients(ctx context.Context, data logNotificationsResourceData, diags *diag.Diagnostics) *awsinternal.LogNotificationClients {
+	clients, err := awsinternal.GetLogNotificationClients(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		addAwsError(diags, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), err)
+		return nil
+	}
+	return clients
+}
+
+func (r logNotificationsResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data logNotificationsResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	clients := r.clients(ctx, data, &resp.Diagnostics)
+	if clients == nil {
+		return
+	}
+	notification, err := awsinternal.CreateLogNotification(ctx, clients, data.spec())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to create log notifications for bucket %s", data.BucketName.Value), err)
+		return
+	}
+	data.ID = types.String{Value: notification.QueueArn}
+	data.TopicArn = types.String{Value: notification.TopicArn}
+	data.QueueArn = types.String{Value: notification.QueueArn}
+	data.QueueURL = types.String{Value: notification.QueueURL}
+	data.Drift = types.String{Value: ""}
+
+	tflog.Trace(ctx, "created log notifications")
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r logNotificationsResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data logNotificationsResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	clients := r.clients(ctx, data, &resp.Diagnostics)
+	if clients == nil {
+		return
+	}
+	status, err := awsinternal.GetLogNotificationStatus(ctx, clients, data.spec())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get log notifications for bucket %s", data.BucketName.Value), err)
+		return
+	}
+	if !status.Topic && !status.Queue {
+		tflog.Warn(ctx, "log notifications not found, removing from state", map[string]interface{}{
+			"bucket_name": data.BucketName.Value,
+			"name":        data.Name.Value,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.Drift = types.String{Value: logNotificationsDrift(status)}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r logNotificationsResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data logNotificationsResourceData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	var state logNotificationsResourceData
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only the consumer role and the topic configuration of the bucket
+	// change in place.
+	clients := r.clients(ctx, data, &resp.Diagnostics)
+	if clients == nil {
+		return
+	}
+	if state.Drift.Value == logNotificationsMissingConfiguration {
+		if err := awsinternal.PutLogTopicConfiguration(ctx, clients, data.spec()); err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to update notifications of bucket %s", data.BucketName.Value), err)
+			return
+		}
+	}
+	if err := awsinternal.UpdateLogQueueConsumer(ctx, clients, data.spec(), data.QueueURL.Value); err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to update policy of queue %s", data.QueueArn.Value), err)
+		return
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r logNotificationsResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data logNotificationsResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	clients := r.clients(ctx, data, &resp.Diagnostics)
+	if clients == nil {
+		return
+	}
+	if err := awsinternal.DeleteLogNotification(ctx, clients, data.spec(), data.QueueURL.Value); err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to delete log notifications for bucket %s", data.BucketName.Value), err)
+		return
+	}
+}
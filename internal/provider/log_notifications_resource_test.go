@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestLogNotificationsSpec(t *testing.T) {
+	data := logNotificationsResourceData{
+		AccountID:    types.String{Value: "234567890123"},
+		BucketName:   types.String{Value: "example-log-archive"},
+		BucketRegion: types.String{Value: "us-east-1"},
+		Name:         types.String{Value: "uptycs-cloudtrail"},
+		Prefix:       types.String{Null: true},
+		RoleArn:      types.String{Value: "arn:aws:iam::123456789012:role/uptcloud"},
+	}
+	expected := awsinternal.LogNotificationSpec{
+		Name:         "uptycs-cloudtrail",
+		AccountID:    "234567890123",
+		BucketName:   "example-log-archive",
+		BucketRegion: "us-east-1",
+		RoleArn:      "arn:aws:iam::123456789012:role/uptcloud",
+	}
+	spec := data.spec()
+	if spec != expected {
+		t.Fatalf("expected %+v, got %+v", expected, spec)
+	}
+	if spec.TopicArn() != "arn:aws:sns:us-east-1:234567890123:uptycs-cloudtrail" || spec.QueueArn() != "arn:aws:sqs:us-east-1:234567890123:uptycs-cloudtrail" {
+		t.Errorf("unexpected topic %s and queue %s", spec.TopicArn(), spec.QueueArn())
+	}
+}
+
+func TestLogNotificationsDrift(t *testing.T) {
+	for _, c := range []struct {
+		status   awsinternal.LogNotificationStatus
+		expected string
+	}{
+		{status: awsinternal.LogNotificationStatus{Topic: true, Queue: true, TopicConfiguration: true}, expected: ""},
+		{status: awsinternal.LogNotificationStatus{Topic: true, Queue: true}, expected: logNotificationsMissingConfiguration},
+		{status: awsinternal.LogNotificationStatus{Queue: true, TopicConfiguration: true}, expected: "missing topic"},
+		{status: awsinternal.LogNotificationStatus{Topic: true}, expected: "missing queue"},
+	} {
+		if drift := logNotificationsDrift(c.status); drift != c.expected {
+			t.Errorf("%+v: expected %q, got %q", c.status, c.expected, drift)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.DataSourceType = orgAccountsDataSourceType{}
+var _ tfsdk.DataSource = orgAccountsDataSource{}
+
+type orgAccountsDataSourceType struct{}
+
+func (t orgAccountsDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Lists the accounts of an AWS organization",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Identifier of the listing",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"organizational_unit_ids": {
+				MarkdownDescription: "Only return accounts contained, directly or through nested units, in one of these roots or organizational units",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"statuses": {
+				MarkdownDescription: "Only return accounts with one of these statuses. Defaults to `ACTIVE`",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"tags": {
+				MarkdownDescription: "Only return accounts having all of these tags",
+				Optional:            true,
+				Type:                types.MapType{ElemType: types.StringType},
+			},
+			"name_regex": {
+				MarkdownDescription: "Only return accounts whose name matches this regular expression",
+				Optional:            true,
+				Type:                types.StringType,
+				Validators:          []tfsdk.AttributeValidator{regexValidator{}},
+			},
+			"exclude_account_ids": {
+				MarkdownDescription: "Account IDs to leave out",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"ids": {
+				MarkdownDescription: "IDs of the matching accounts",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"accounts": {
+				MarkdownDescription: "Matching accounts",
+				Computed:            true,
+				Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+					"id": {
+						MarkdownDescription: "AWS account ID",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"name": {
+						MarkdownDescription: "Account name",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"email": {
+						MarkdownDescription: "Email address of the account owner",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"status": {
+						MarkdownDescription: "Account status",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"joined_timestamp": {
+						MarkdownDescription: "Date the account joined the organization, in RFC 3339 format",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"parent_id": {
+						MarkdownDescription: "Root or organizational unit directly containing the account",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+				}),
+			},
+		},
+	}, nil
+}
+
+func (t orgAccountsDataSourceType) NewDataSource(ctx context.Context, in tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return orgAccountsDataSource{
+		provider: provider,
+	}, diags
+}
+
+type orgAccountsDataSourceData struct {
+	ID                    types.String      `tfsdk:"id"`
+	ProfileName           types.String      `tfsdk:"profile_name"`
+	OrganizationalUnitIDs []string          `tfsdk:"organizational_unit_ids"`
+	Statuses              []string          `tfsdk:"statuses"`
+	Tags                  map[string]string `tfsdk:"tags"`
+	NameRegex             types.String      `tfsdk:"name_regex"`
+	ExcludeAccountIDs     []string          `tfsdk:"exclude_account_ids"`
+	IDs                   []string          `tfsdk:"ids"`
+	Accounts              []orgAccountData  `tfsdk:"accounts"`
+}
+
+type orgAccountData struct {
+	ID              types.String `tfsdk:"id"`
+	Name            types.String `tfsdk:"name"`
+	Email           types.String `tfsdk:"email"`
+	Status          types.String `tfsdk:"status"`
+	JoinedTimestamp types.String `tfsdk:"joined_timestamp"`
+	ParentID        types.String `tfsdk:"parent_id"`
+}
+
+// filter returns the account filter described by d. Only active accounts
+// match when no statuses are set.
+func (d orgAccountsDataSourceData) filter() awsinternal.AccountFilter {
+	filter := awsinternal.AccountFilter{
+		ParentIDs:  d.OrganizationalUnitIDs,
+		Statuses:   d.Statuses,
+		Tags:       d.Tags,
+		ExcludeIDs: d.ExcludeAccountIDs,
+		// parent_id is part of every account
+		ResolveParents: true,
+	}
+	if filter.Statuses == nil {
+		filter.Statuses = []string{awsinternal.AccountStatusActive}
+	}
+	if !d.NameRegex.Null && d.NameRegex.Value != "" {
+		// the expression has already been checked by regexValidator
+		filter.NameRegex = regexp.MustCompile(d.NameRegex.Value)
+	}
+	return filter
+}
+
+// setAccounts sets the ids and accounts of d to the matching accounts.
+func (d *orgAccountsDataSourceData) setAccounts(accounts []awsinternal.Account) {
+	d.IDs = make([]string, 0, len(accounts))
+	d.Accounts = make([]orgAccountData, 0, len(accounts))
+	for _, account := range accounts {
+		joined := ""
+		if !account.JoinedTimestamp.IsZero() {
+			joined = account.JoinedTimestamp.UTC().Format(time.RFC3339)
+		}
+		d.IDs = append(d.IDs, account.ID)
+		d.Accounts = append(d.Accounts, orgAccountData{
+			ID:              types.String{Value: account.ID},
+			Name:            types.String{Value: account.Name},
+			Email:           types.String{Value: account.Email},
+			Status:          types.String{Value: account.Status},
+			JoinedTimestamp: types.String{Value: joined},
+			ParentID:        types.String{Value: account.ParentID},
+		})
+	}
+}
+
+type orgAccountsDataSource struct {
+	provider provider
+}
+
+func (d orgAccountsDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var data orgAccountsDataSourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	accounts, err := d.provider.orgInventory.FilterAccounts(ctx, data.ProfileName.Value, data.filter())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to list organization accounts with profile %s", data.ProfileName.Value), err)
+		return
+	}
+
+	data.ID = types.String{Value: data.ProfileName.Value}
+	data.setAccounts(accounts)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
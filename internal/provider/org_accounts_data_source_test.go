@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestOrgAccountsFilter(t *testing.T) {
+	filter := orgAccountsDataSourceData{NameRegex: types.String{Null: true}}.filter()
+	if !reflect.DeepEqual(filter.Statuses, []string{awsinternal.AccountStatusActive}) {
+		t.Errorf("expected only active accounts by default, got %v", filter.Statuses)
+	}
+	if filter.NameRegex != nil || !filter.ResolveParents {
+		t.Errorf("unexpected default filter %+v", filter)
+	}
+
+	data := orgAccountsDataSourceData{
+		OrganizationalUnitIDs: []string{"ou-abcd-12345678"},
+		Statuses:              []string{"ACTIVE", "SUSPENDED"},
+		Tags:                  map[string]string{"env": "prod"},
+		NameRegex:             types.String{Value: "^prod-"},
+		ExcludeAccountIDs:     []string{"123456789012"},
+	}
+	filter = data.filter()
+	if !reflect.DeepEqual(filter.ParentIDs, data.OrganizationalUnitIDs) ||
+		!reflect.DeepEqual(filter.Statuses, data.Statuses) ||
+		!reflect.DeepEqual(filter.Tags, data.Tags) ||
+		!reflect.DeepEqual(filter.ExcludeIDs, data.ExcludeAccountIDs) {
+		t.Errorf("unexpected filter %+v", filter)
+	}
+	if filter.NameRegex == nil || !filter.NameRegex.MatchString("prod-billing") || filter.NameRegex.MatchString("dev-prod") {
+		t.Errorf("unexpected name regex %v", filter.NameRegex)
+	}
+}
+
+func TestOrgAccountsSetAccounts(t *testing.T) {
+	var data orgAccountsDataSourceData
+	data.setAccounts(nil)
+	if data.IDs == nil || data.Accounts == nil {
+		t.Error("expected empty lists rather than null without matching accounts")
+	}
+
+	joined := time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	data.setAccounts([]awsinternal.Account{
+		{ID: "123456789012", Name: "prod-billing", Email: "billing@example.com", Status: "ACTIVE", JoinedTimestamp: joined, ParentID: "ou-abcd-12345678"},
+		{ID: "234567890123", Name: "prod-logs", Status: "ACTIVE", ParentID: "r-abcd"},
+	})
+	if !reflect.DeepEqual(data.IDs, []string{"123456789012", "234567890123"}) {
+		t.Errorf("unexpected ids %v", data.IDs)
+	}
+	if got := data.Accounts[0].JoinedTimestamp.Value; got != "2021-03-04T04:06:07Z" {
+		t.Errorf("expected the joined timestamp in UTC, got %s", got)
+	}
+	if got := data.Accounts[1].JoinedTimestamp.Value; got != "" {
+		t.Errorf("expected an empty joined timestamp when unknown, got %s", got)
+	}
+	if got := data.Accounts[1].ParentID.Value; got != "r-abcd" {
+		t.Errorf("unexpected parent %s", got)
+	}
+}
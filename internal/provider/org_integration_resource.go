@@ -0,0 +1,880 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = orgIntegrationResourceType{}
+var _ tfsdk.Resource = orgIntegrationResource{}
+var _ tfsdk.ResourceWithModifyPlan = orgIntegrationResource{}
+var _ tfsdk.ResourceWithValidateConfig = orgIntegrationResource{}
+
+// Status of an account in the accounts attribute of
+// uptycscspm_org_integration. Every status other than accountStatusEnrolled
+// is reconciled on the next apply.
+const (
+	accountStatusEnrolled = "enrolled"
+	accountStatusFailed   = "failed"
+	accountStatusMissing  = "missing"
+	accountStatusDrifted  = "drifted"
+
+	// accountStatusPending marks an account not worked on yet: a new account
+	// of the targeted organizational units found by auto_enroll, or an
+	// account skipped after too many failures in the others.
+	accountStatusPending = "pending"
+	// accountStatusPendingRemoval marks an account no longer targeted, for
+	// example closed or suspended, found by auto_unenroll.
+	accountStatusPendingRemoval = "pending_removal"
+)
+
+// Values of failure_mode.
+const (
+	failureModeFailFast  = "fail_fast"
+	failureModeContinue  = "continue"
+	failureModeThreshold = "threshold"
+)
+
+type orgIntegrationResourceType struct{}
+
+func (t orgIntegrationResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Uptycs integration role in every targeted account of an AWS organization",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Integration name",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name of the organization management account",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"integration_name": {
+				MarkdownDescription: "Integration name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"upt_account_id": {
+				MarkdownDescription: "Uptycs AWS account ID",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"external_id": {
+				MarkdownDescription: "External ID",
+				Required:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+			"bucket_name": {
+				MarkdownDescription: "Cloudtrail Bucket",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"bucket_region": {
+				MarkdownDescription: "Cloudtrail Bucket Region",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"bucket_prefix": {
+				MarkdownDescription: "Key prefix of the CloudTrail logs in the bucket, such as the `prefix` of `uptycscspm_org_trail`. " +
+					"The role can only read the logs under it. Defaults to the whole bucket",
+				Optional: true,
+				Type:     types.StringType,
+			},
+			"policy_document": {
+				MarkdownDescription: "Uptycs ReadOnly Policy",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"account_ids": {
+				MarkdownDescription: "Accounts to integrate. When neither `account_ids` nor `organizational_unit_ids` is set, every active account of the organization is integrated",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"organizational_unit_ids": {
+				MarkdownDescription: "Integrate the active accounts contained, directly or through nested units, in these roots or organizational units",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"auto_enroll": {
+				MarkdownDescription: "Plan the enrollment of accounts created in, or moved to, the targeted organizational units since the last apply. Otherwise such accounts are only enrolled once the targeted organizational units change",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"auto_unenroll": {
+				MarkdownDescription: "Plan the removal of accounts that were closed, suspended or moved out of the targeted organizational units since the last apply. Otherwise such accounts are kept in state until they are removed from the configuration",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"concurrency": {
+				MarkdownDescription: fmt.Sprintf("Number of accounts worked on at once. Defaults to `%d`", awsinternal.DefaultConcurrency),
+				Optional:            true,
+				Type:                types.Int64Type,
+				Validators:          []tfsdk.AttributeValidator{int64BetweenValidator{min: 1, max: 100}},
+			},
+			"failure_mode": {
+				MarkdownDescription: "How failures in some accounts affect the others: `fail_fast` stops at the first failure, " +
+					"`continue` works on every account, and `threshold` stops once more than `failure_threshold` accounts failed. " +
+					"Under `continue`, and within the threshold, failures are reported as warnings and the failed accounts are recorded as `failed` " +
+					"and retried in place on the next apply. Under `fail_fast`, or once the threshold is exceeded, the apply fails, except for the creation of an integration that " +
+					"enrolled some accounts: they are kept, the failures are warnings, and the next apply retries the other accounts. Defaults to `continue`",
+				Optional:   true,
+				Type:       types.StringType,
+				Validators: []tfsdk.AttributeValidator{oneOfValidator{values: []string{failureModeFailFast, failureModeContinue, failureModeThreshold}}},
+			},
+			"failure_threshold": {
+				MarkdownDescription: "Number, or percentage of the accounts with a `%` suffix, of failures tolerated when `failure_mode` is `threshold`. " +
+					"Failures within the threshold are reported as warnings",
+				Optional:   true,
+				Type:       types.StringType,
+				Validators: []tfsdk.AttributeValidator{failureThresholdValidator{}},
+			},
+			"accounts": {
+				MarkdownDescription: "Integration status of each targeted account",
+				Computed:            true,
+				Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+					"account_id": {
+						MarkdownDescription: "AWS account ID",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"status": {
+						MarkdownDescription: "`enrolled`, or `failed`, `missing`, `drifted`, `pending` or `pending_removal` when the account is reconciled on the next apply",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"role_arn": {
+						MarkdownDescription: "Role ARN",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+					"last_error": {
+						MarkdownDescription: "Error of the last operation in the account, empty when it succeeded",
+						Computed:            true,
+						Type:                types.StringType,
+					},
+				}),
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t orgIntegrationResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return orgIntegrationResource{
+		provider: provider,
+	}, diags
+}
+
+type orgIntegrationResourceData struct {
+	ID                    types.String                `tfsdk:"id"`
+	ProfileName           types.String                `tfsdk:"profile_name"`
+	IntegrationName       types.String                `tfsdk:"integration_name"`
+	UptAccountID          types.String                `tfsdk:"upt_account_id"`
+	ExternalID            types.String                `tfsdk:"external_id"`
+	BucketName            types.String                `tfsdk:"bucket_name"`
+	BucketRegion          types.String                `tfsdk:"bucket_region"`
+	BucketPrefix          types.String                `tfsdk:"bucket_prefix"`
+	PolicyDocument        types.String                `tfsdk:"policy_document"`
+	OrgAccessRoleName     types.String                `tfsdk:"org_access_role_name"`
+	AccountIDs            []string                    `tfsdk:"account_ids"`
+	OrganizationalUnitIDs []string                    `tfsdk:"organizational_unit_ids"`
+	AutoEnroll            types.Bool                  `tfsdk:"auto_enroll"`
+	AutoUnenroll          types.Bool                  `tfsdk:"auto_unenroll"`
+	Concurrency           types.Int64                 `tfsdk:"concurrency"`
+	FailureMode           types.String                `tfsdk:"failure_mode"`
+	FailureThreshold      types.String                `tfsdk:"failure_threshold"`
+	Accounts              []orgIntegrationAccountData `tfsdk:"accounts"`
+	Timeouts              []timeoutsData              `tfsdk:"timeouts"`
+}
+
+type orgIntegrationAccountData struct {
+	AccountID types.String `tfsdk:"account_id"`
+	Status    types.String `tfsdk:"status"`
+	RoleArn   types.String `tfsdk:"role_arn"`
+	LastError types.String `tfsdk:"last_error"`
+}
+
+// roleChanged reports whether the settings of the role created in each
+// account differ between d and other.
+func (d orgIntegrationResourceData) roleChanged(other orgIntegrationResourceData) bool {
+	return d.IntegrationName.Value != other.IntegrationName.Value ||
+		d.UptAccountID.Value != other.UptAccountID.Value ||
+		d.ExternalID.Value != other.ExternalID.Value ||
+		d.BucketName.Value != other.BucketName.Value ||
+		d.BucketRegion.Value != other.BucketRegion.Value ||
+		d.BucketPrefix.Value != other.BucketPrefix.Value ||
+		d.PolicyDocument.Value != other.PolicyDocument.Value ||
+		d.OrgAccessRoleName.Value != other.OrgAccessRoleName.Value
+}
+
+// unitsChanged reports whether the organizational units targeted by d, or
+// the whole organization when no account is listed, differ from other's.
+func (d orgIntegrationResourceData) unitsChanged(other orgIntegrationResourceData) bool {
+	if (len(d.AccountIDs) == 0) != (len(other.AccountIDs) == 0) || len(d.OrganizationalUnitIDs) != len(other.OrganizationalUnitIDs) {
+		return true
+	}
+	for _, id := range d.OrganizationalUnitIDs {
+		if !containsString(other.OrganizationalUnitIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d orgIntegrationResourceData) concurrency() int {
+	if d.Concurrency.Null || d.Concurrency.Unknown {
+		return awsinternal.DefaultConcurrency
+	}
+	return int(d.Concurrency.Value)
+}
+
+// maxFailures returns the number of failures allowed by the failure mode in
+// a rollout over total accounts, and whether failures within that number are
+// tolerated rather than reported as errors.
+func (d orgIntegrationResourceData) maxFailures(total int) (int, bool) {
+	switch d.FailureMode.Value {
+	case failureModeFailFast:
+		return 0, false
+	case failureModeThreshold:
+		// the value has already been checked by failureThresholdValidator
+		threshold, percent, _ := parseFailureThreshold(d.FailureThreshold.Value)
+		return awsinternal.MaxFailures(threshold, percent, total), true
+	}
+	return awsinternal.Unlimited, true
+}
+
+// addRolloutDiagnostics reports the accounts of results that failed or were
+// not attempted. action describes the operation in an account. keep reports
+// every failure as a warning, for the creation of an integration that enrolled
+// some accounts: an error would taint the resource, and the next apply would
+// re-create the roles of the enrolled accounts instead of retrying the others.
+func addRolloutDiagnostics(diags *diag.Diagnostics, results []awsinternal.AccountResult, maxFailures int, tolerated bool, keep bool, action func(accountID string) string) {
+	failed, notAttempted := 0, 0
+	for _, result := range results {
+		switch {
+		case result.NotAttempted():
+			notAttempted++
+		case result.Err != nil:
+			failed++
+		}
+	}
+	exceeded := maxFailures != awsinternal.Unlimited && failed > maxFailures
+	for _, result := range results {
+		if result.Err == nil || result.NotAttempted() {
+			continue
+		}
+		if keep || (tolerated && !exceeded) {
+			addAwsWarning(diags, action(result.AccountID), result.Err)
+		} else {
+			addAwsError(diags, action(result.AccountID), result.Err)
+		}
+	}
+	if notAttempted > 0 {
+		summary := "Rollout Stopped"
+		detail := fmt.Sprintf("%d accounts were not attempted because %d accounts failed, more than the %d allowed by failure_mode. "+
+			"They will be attempted on the next apply.", notAttempted, failed, maxFailures)
+		if keep {
+			diags.AddWarning(summary, detail)
+		} else {
+			diags.AddError(summary, detail)
+		}
+	}
+}
+
+func newOrgIntegrationAccount(result awsinternal.AccountResult) orgIntegrationAccountData {
+	if result.NotAttempted() {
+		return orgIntegrationAccountData{
+			AccountID: types.String{Value: result.AccountID},
+			Status:    types.String{Value: accountStatusPending},
+			RoleArn:   types.String{Value: result.RoleArn},
+			LastError: types.String{Value: ""},
+		}
+	}
+	if result.Err != nil {
+		return orgIntegrationAccountData{
+			AccountID: types.String{Value: result.AccountID},
+			Status:    types.String{Value: accountStatusFailed},
+			RoleArn:   types.String{Value: result.RoleArn},
+			LastError: types.String{Value: awsErrorMessage(result.Err)},
+		}
+	}
+	return orgIntegrationAccountData{
+		AccountID: types.String{Value: result.AccountID},
+		Status:    types.String{Value: accountStatusEnrolled},
+		RoleArn:   types.String{Value: result.RoleArn},
+		LastError: types.String{Value: ""},
+	}
+}
+
+type orgIntegrationResource struct {
+	provider provider
+}
+
+// targetAccounts returns the sorted IDs of the accounts data integrates.
+func (r orgIntegrationResource) targetAccounts(ctx context.Context, data orgIntegrationResourceData) ([]string, error) {
+	targets := make(map[string]bool)
+	for _, id := range data.AccountIDs {
+		if data.AutoUnenroll.Value {
+			active, err := r.isActiveMember(ctx, data, id)
+			if err != nil {
+				return nil, err
+			}
+			if !active {
+				continue
+			}
+		}
+		targets[id] = true
+	}
+	if len(data.OrganizationalUnitIDs) > 0 || len(data.AccountIDs) == 0 {
+		accounts, err := r.provider.orgInventory.FilterAccounts(ctx, data.ProfileName.Value, awsinternal.AccountFilter{
+			ParentIDs: data.OrganizationalUnitIDs,
+			Statuses:  []string{awsinternal.AccountStatusActive},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, account := range accounts {
+			targets[account.ID] = true
+		}
+	}
+	ids := make([]string, 0, len(targets))
+	for id := range targets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// previousRole returns the settings prior used for the role of account,
+// taking the role name from the role ARN when one was recorded.
+func previousRole(prior orgIntegrationResourceData, account orgIntegrationAccountData) orgIntegrationResourceData {
+	if i := strings.LastIndex(account.RoleArn.Value, "/"); i >= 0 {
+		prior.IntegrationName = types.String{Value: account.RoleArn.Value[i+1:]}
+	}
+	return prior
+}
+
+// isActiveMember reports whether accountID is an active account of the
+// organization of data's profile.
+func (r orgIntegrationResource) isActiveMember(ctx context.Context, data orgIntegrationResourceData, accountID string) (bool, error) {
+	account, err := r.provider.orgInventory.Account(ctx, data.ProfileName.Value, accountID)
+	if err != nil {
+		return false, err
+	}
+	return account != nil && account.Status == awsinternal.AccountStatusActive, nil
+}
+
+// enroll creates or completes the integration role in accountID.
+func (r orgIntegrationResource) enroll(ctx context.Context, data orgIntegrationResourceData, accountID string) (string, error) {
+	svc, err := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", accountID, data.OrgAccessRoleName.Value, false)
+	if err != nil {
+		return "", err
+	}
+	return awsinternal.CreateUptycsCspmResources(ctx,
+		svc,
+		data.IntegrationName.Value,
+		awsinternal.Trust{
+			UptAccountIDs: []string{data.UptAccountID.Value},
+			ExternalIDs:   []string{data.ExternalID.Value},
+		},
+		data.BucketName.Value,
+		data.BucketRegion.Value,
+		data.BucketPrefix.Value,
+		data.ProfileName.Value,
+		accountID,
+		data.PolicyDocument.Value,
+		data.OrgAccessRoleName.Value,
+		false)
+}
+
+// unenroll deletes the integration role from accountID. A role that no
+// longer exists is not an error.
+func (r orgIntegrationResource) unenroll(ctx context.Context, data orgIntegrationResourceData, accountID string) error {
+	svc, err := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", accountID, data.OrgAccessRoleName.Value, false)
+	if err != nil {
+		return err
+	}
+	err = awsinternal.DeleteUptycsCspmResources(ctx, svc, data.IntegrationName.Value)
+	if awsinternal.IsKind(err, awsinternal.ErrNoSuchEntity) {
+		return nil
+	}
+	return err
+}
+
+func (r orgIntegrationResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data orgIntegrationResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	targets, err := r.targetAccounts(ctx, data)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+		return
+	}
+
+	maxFailures, tolerated := data.maxFailures(len(targets))
+	results := awsinternal.ForEachAccount(ctx, targets, data.concurrency(), maxFailures, func(ctx context.Context, accountID string) (string, error) {
+		return r.enroll(ctx, data, accountID)
+	})
+	data.Accounts = make([]orgIntegrationAccountData, 0, len(results))
+	enrolled := 0
+	for _, result := range results {
+		if result.Err == nil {
+			enrolled++
+		}
+		data.Accounts = append(data.Accounts, newOrgIntegrationAccount(result))
+	}
+	addRolloutDiagnostics(&resp.Diagnostics, results, maxFailures, tolerated, enrolled > 0, func(accountID string) string {
+		return fmt.Sprintf("Unable to create uptycscspm role in account %s", accountID)
+	})
+	if enrolled == 0 && resp.Diagnostics.HasError() {
+		// nothing was created, leave the resource out of state
+		return
+	}
+	// The enrolled accounts are recorded even when some accounts failed, the
+	// failed accounts keep their status and are retried in place on the next
+	// apply.
+	data.ID = types.String{Value: data.IntegrationName.Value}
+
+	tflog.Trace(ctx, "created an organization integration", map[string]interface{}{
+		"accounts": len(data.Accounts),
+	})
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r orgIntegrationResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data orgIntegrationResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	if data.AutoEnroll.Value || data.AutoUnenroll.Value {
+		if err := r.reconcileInventory(ctx, &data); err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+			return
+		}
+	}
+
+	var enrolled []string
+	for _, account := range data.Accounts {
+		if account.Status.Value == accountStatusEnrolled {
+			enrolled = append(enrolled, account.AccountID.Value)
+		}
+	}
+	statuses := make(map[string]orgIntegrationAccountData, len(enrolled))
+	var mu sync.Mutex
+	awsinternal.ForEachAccount(ctx, enrolled, data.concurrency(), awsinternal.Unlimited, func(ctx context.Context, accountID string) (string, error) {
+		account := r.readAccount(ctx, data, accountID)
+		mu.Lock()
+		statuses[accountID] = account
+		mu.Unlock()
+		return "", nil
+	})
+	for i, account := range data.Accounts {
+		if status, found := statuses[account.AccountID.Value]; found {
+			data.Accounts[i] = status
+		}
+	}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+// reconcileInventory compares the live organization with the accounts in
+// state. With auto_enroll, newly targeted accounts are added as pending; with
+// auto_unenroll, accounts no longer targeted are marked for removal. Both
+// statuses make ModifyPlan plan an update.
+func (r orgIntegrationResource) reconcileInventory(ctx context.Context, data *orgIntegrationResourceData) error {
+	targets, err := r.targetAccounts(ctx, *data)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(data.Accounts))
+	for i, account := range data.Accounts {
+		known[account.AccountID.Value] = true
+		if data.AutoUnenroll.Value && !containsString(targets, account.AccountID.Value) {
+			tflog.Info(ctx, "account is no longer targeted, planning its removal", map[string]interface{}{
+				"account_id": account.AccountID.Value,
+			})
+			data.Accounts[i].Status = types.String{Value: accountStatusPendingRemoval}
+		}
+	}
+	if !data.AutoEnroll.Value {
+		return nil
+	}
+	for _, id := range targets {
+		if known[id] {
+			continue
+		}
+		tflog.Info(ctx, "new account found in the organization, planning its enrollment", map[string]interface{}{
+			"account_id": id,
+		})
+		data.Accounts = append(data.Accounts, orgIntegrationAccountData{
+			AccountID: types.String{Value: id},
+			Status:    types.String{Value: accountStatusPending},
+			RoleArn:   types.String{Value: ""},
+			LastError: types.String{Value: ""},
+		})
+	}
+	sort.Slice(data.Accounts, func(i, j int) bool {
+		return data.Accounts[i].AccountID.Value < data.Accounts[j].AccountID.Value
+	})
+	return nil
+}
+
+// readAccount returns the current state of the integration role in an
+// enrolled account. An account that cannot be read keeps its status, the
+// failure is only logged so that one unreachable account does not block
+// planning the others.
+func (r orgIntegrationResource) readAccount(ctx context.Context, data orgIntegrationResourceData, accountID string) orgIntegrationAccountData {
+	account := orgIntegrationAccountData{
+		AccountID: types.String{Value: accountID},
+		Status:    types.String{Value: accountStatusEnrolled},
+		LastError: types.String{Value: ""},
+	}
+	svc, err := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", accountID, data.OrgAccessRoleName.Value, false)
+	var status *awsinternal.RoleStatus
+	if err == nil {
+		status, err = awsinternal.GetIntegrationRoleStatus(ctx, svc, data.IntegrationName.Value)
+	}
+	switch {
+	case awsinternal.IsKind(err, awsinternal.ErrNoSuchEntity):
+		// The role was deleted outside of Terraform, plan to re-create it.
+		account.Status = types.String{Value: accountStatusMissing}
+		account.RoleArn = types.String{Value: ""}
+		account.LastError = types.String{Value: "integration role not found"}
+		return account
+	case err != nil:
+		tflog.Warn(ctx, "unable to read uptycscspm role", map[string]interface{}{
+			"account_id": accountID,
+			"error":      err.Error(),
+		})
+		for _, prior := range data.Accounts {
+			if prior.AccountID.Value == accountID {
+				return prior
+			}
+		}
+		return account
+	}
+	account.RoleArn = types.String{Value: status.Arn}
+
+	bucketPolicyArn := ""
+	if data.BucketName.Value != "" {
+		bucketPolicyArn = awsinternal.GetCloudtrailBucketPolicyArn(accountID, data.IntegrationName.Value)
+	}
+	if missing := status.MissingPolicies(bucketPolicyArn); len(missing) > 0 {
+		account.Status = types.String{Value: accountStatusDrifted}
+		account.LastError = types.String{Value: "missing policies: " + strings.Join(missing, ", ")}
+	}
+	return account
+}
+
+func (r orgIntegrationResource) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var accounts []orgIntegrationAccountData
+	diags := req.State.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("accounts"), &accounts)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, account := range accounts {
+		if account.Status.Value != accountStatusEnrolled {
+			// Plan an update so that the next apply reconciles the account.
+			diags = resp.Plan.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("accounts"), types.List{
+				Unknown:  true,
+				ElemType: orgIntegrationAccountType,
+			})
+			resp.Diagnostics.Append(diags...)
+			return
+		}
+	}
+}
+
+var orgIntegrationAccountType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"account_id": types.StringType,
+		"status":     types.StringType,
+		"role_arn":   types.StringType,
+		"last_error": types.StringType,
+	},
+}
+
+func (r orgIntegrationResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data, prior orgIntegrationResourceData
+
+	// accounts is unknown in the plan, the configuration holds every other
+	// value that is needed
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &prior)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	targets, err := r.targetAccounts(ctx, data)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+		return
+	}
+
+	priorAccounts := make(map[string]orgIntegrationAccountData, len(prior.Accounts))
+	for _, account := range prior.Accounts {
+		priorAccounts[account.AccountID.Value] = account
+	}
+	roleChanged := data.roleChanged(prior)
+	unitsChanged := data.unitsChanged(prior)
+
+	if !data.AutoEnroll.Value && !unitsChanged {
+		// Without auto_enroll, accounts that joined the targeted units since
+		// the last apply are left out until the configuration changes.
+		configured := make([]string, 0, len(targets))
+		for _, id := range targets {
+			if _, found := priorAccounts[id]; found || containsString(data.AccountIDs, id) {
+				configured = append(configured, id)
+			}
+		}
+		targets = configured
+	}
+
+	// Accounts that are already enrolled with the current settings are left
+	// untouched, the others are enrolled and the accounts no longer targeted
+	// are unenrolled.
+	kept := make(map[string]orgIntegrationAccountData)
+	removed := make(map[string]bool)
+	var work []string
+	for _, id := range targets {
+		account, found := priorAccounts[id]
+		if found && account.Status.Value == accountStatusEnrolled && !roleChanged {
+			kept[id] = account
+			continue
+		}
+		work = append(work, id)
+	}
+	for _, account := range prior.Accounts {
+		if containsString(targets, account.AccountID.Value) {
+			continue
+		}
+		deselected := containsString(prior.AccountIDs, account.AccountID.Value) && !containsString(data.AccountIDs, account.AccountID.Value)
+		if !data.AutoUnenroll.Value && !unitsChanged && !deselected {
+			// Without auto_unenroll, accounts that were closed, suspended or
+			// moved out of the targeted units are kept as they are until the
+			// configuration changes.
+			if account.Status.Value == accountStatusPendingRemoval {
+				if account.RoleArn.Value == "" {
+					continue
+				}
+				// marked by an earlier auto_unenroll, the next read checks
+				// the role again
+				account.Status = types.String{Value: accountStatusEnrolled}
+				account.LastError = types.String{Value: ""}
+			}
+			kept[account.AccountID.Value] = account
+			continue
+		}
+		// The role of an account that was closed, suspended or that left
+		// the organization cannot be reached anymore, it is only dropped
+		// from state.
+		active, err := r.isActiveMember(ctx, prior, account.AccountID.Value)
+		if err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+			return
+		}
+		if !active {
+			continue
+		}
+		removed[account.AccountID.Value] = true
+		work = append(work, account.AccountID.Value)
+	}
+
+	maxFailures, tolerated := data.maxFailures(len(work))
+	results := awsinternal.ForEachAccount(ctx, work, data.concurrency(), maxFailures, func(ctx context.Context, accountID string) (string, error) {
+		account, found := priorAccounts[accountID]
+		if removed[accountID] {
+			return account.RoleArn.Value, r.unenroll(ctx, previousRole(prior, account), accountID)
+		}
+		// A role left in place by a failed or skipped account may have
+		// been created with settings older than the previous apply.
+		stale := account.Status.Value != accountStatusEnrolled && account.Status.Value != accountStatusDrifted && account.RoleArn.Value != ""
+		if found && (roleChanged || stale) {
+			// Re-create the role like uptycscspm_role does, removing the
+			// role created with the previous settings first.
+			if err := r.unenroll(ctx, previousRole(prior, account), accountID); err != nil {
+				return account.RoleArn.Value, err
+			}
+		}
+		return r.enroll(ctx, data, accountID)
+	})
+
+	updated := make(map[string]orgIntegrationAccountData, len(targets))
+	for id, account := range kept {
+		updated[id] = account
+	}
+	addRolloutDiagnostics(&resp.Diagnostics, results, maxFailures, tolerated, false, func(accountID string) string {
+		if removed[accountID] {
+			return fmt.Sprintf("Unable to delete uptycscspm role in account %s", accountID)
+		}
+		return fmt.Sprintf("Unable to update uptycscspm role in account %s", accountID)
+	})
+	for _, result := range results {
+		account := newOrgIntegrationAccount(result)
+		if result.NotAttempted() {
+			// the role of the previous apply, if any, is still in place
+			account.RoleArn = priorAccounts[result.AccountID].RoleArn
+		}
+		if removed[result.AccountID] {
+			if result.Err == nil {
+				continue
+			}
+			// an account that could not be unenrolled stays in state so
+			// that the removal is retried on the next apply
+			if result.NotAttempted() {
+				account.Status = types.String{Value: accountStatusPendingRemoval}
+			}
+		}
+		updated[result.AccountID] = account
+	}
+	data.Accounts = make([]orgIntegrationAccountData, 0, len(updated))
+	for _, account := range updated {
+		data.Accounts = append(data.Accounts, account)
+	}
+	sort.Slice(data.Accounts, func(i, j int) bool {
+		return data.Accounts[i].AccountID.Value < data.Accounts[j].AccountID.Value
+	})
+	data.ID = types.String{Value: data.IntegrationName.Value}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r orgIntegrationResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data orgIntegrationResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	accountIDs := make([]string, 0, len(data.Accounts))
+	for _, account := range data.Accounts {
+		if account.Status.Value == accountStatusPending && account.RoleArn.Value == "" {
+			// nothing was created in the account yet
+			continue
+		}
+		if account.Status.Value == accountStatusPendingRemoval {
+			active, err := r.isActiveMember(ctx, data, account.AccountID.Value)
+			if err != nil {
+				addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+				return
+			}
+			if !active {
+				// the account cannot be reached anymore
+				continue
+			}
+		}
+		accountIDs = append(accountIDs, account.AccountID.Value)
+	}
+	results := awsinternal.ForEachAccount(ctx, accountIDs, data.concurrency(), awsinternal.Unlimited, func(ctx context.Context, accountID string) (string, error) {
+		for _, account := range data.Accounts {
+			if account.AccountID.Value == accountID {
+				return "", r.unenroll(ctx, previousRole(data, account), accountID)
+			}
+		}
+		return "", nil
+	})
+	for _, result := range results {
+		if result.Err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to delete uptycscspm role in account %s", result.AccountID), result.Err)
+		}
+	}
+}
+
+func (r orgIntegrationResource) ValidateConfig(ctx context.Context, req tfsdk.ValidateResourceConfigRequest, resp *tfsdk.ValidateResourceConfigResponse) {
+	var mode, threshold types.String
+
+	diags := req.Config.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("failure_mode"), &mode)
+	resp.Diagnostics.Append(diags...)
+	diags = req.Config.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("failure_threshold"), &threshold)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() || mode.Unknown || threshold.Unknown {
+		return
+	}
+
+	if mode.Value == failureModeThreshold && threshold.Null {
+		resp.Diagnostics.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("failure_threshold"),
+			"Missing Failure Threshold", "failure_threshold is required when failure_mode is threshold.")
+	}
+	if mode.Value != failureModeThreshold && !threshold.Null {
+		resp.Diagnostics.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("failure_threshold"),
+			"Unused Failure Threshold", "failure_threshold is only used when failure_mode is threshold.")
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccOrgIntegrationResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccOrgIntegrationResourceConfig("noprofile", "account_ids = [\"123456789012\", \"234567890123\"]\n  failure_mode = \"fail_fast\""),
+				// Expect to fail as we cannot contact AWS with fake accounts,
+				// failures are only warnings under the default failure_mode
+				ExpectError: regexp.MustCompile("Unable to create uptycscspm role in account 123456789012"),
+			},
+			{
+				Config:      testAccOrgIntegrationResourceConfig("noprofile", `organizational_unit_ids = ["ou-abcd-12345678"]`),
+				ExpectError: regexp.MustCompile("Unable to get account list from organization with profile noprofile"),
+			},
+			{
+				// auto_unenroll checks the membership of the listed accounts
+				Config:      testAccOrgIntegrationResourceConfig("noprofile", "account_ids = [\"123456789012\"]\n  auto_unenroll = true"),
+				ExpectError: regexp.MustCompile("Unable to get account list from organization with profile noprofile"),
+			},
+			{
+				Config:      testAccOrgIntegrationResourceConfig("noprofile", "account_ids = [\"123456789012\"]\n  failure_mode = \"threshold\""),
+				ExpectError: regexp.MustCompile("failure_threshold is required when failure_mode is threshold"),
+			},
+			{
+				Config:      testAccOrgIntegrationResourceConfig("noprofile", "account_ids = [\"123456789012\"]\n  failure_mode = \"threshold\"\n  failure_threshold = \"150%\""),
+				ExpectError: regexp.MustCompile("Invalid Failure Threshold"),
+			},
+			{
+				Config:      testAccOrgIntegrationResourceConfig("noprofile", `concurrency = 0`),
+				ExpectError: regexp.MustCompile("value must be between 1 and 100"),
+			},
+		},
+	})
+}
+
+func testAccOrgIntegrationResourceConfig(profile string, targets string) string {
+	return fmt.Sprintf(`
+resource "uptycscspm_org_integration" "test" {
+  profile_name = %[1]q
+  upt_account_id = "012345678912"
+  integration_name = "uptcloud"
+  external_id = "6a9375c1-47c0-470c-9217-d2f9d2d185f1"
+  bucket_name = "uptycs-test-bucket"
+  bucket_region = "us-east-1"
+  policy_document = ""
+  %[2]s
+}
+`, profile, targets)
+}
@@ -0,0 +1,325 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.ResourceType = orgTrailResourceType{}
+var _ tfsdk.Resource = orgTrailResource{}
+
+type orgTrailResourceType struct{}
+
+func (t orgTrailResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	replaced := []tfsdk.AttributePlanModifier{tfsdk.RequiresReplace()}
+	computed := []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()}
+	return tfsdk.Schema{
+		MarkdownDescription: "Organization CloudTrail trail and its log bucket, readable by the Uptycs integration roles of the listed accounts",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Trail ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name of the organization management account",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"trail_name": {
+				MarkdownDescription: "Trail name",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"bucket_name": {
+				MarkdownDescription: "Name of the log bucket to create",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"bucket_region": {
+				MarkdownDescription: "Region of the log bucket, which is also the home region of the trail",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"s3_key_prefix": {
+				MarkdownDescription: "Key prefix of the logs in the bucket",
+				Optional:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"integration_name": {
+				MarkdownDescription: "Integration name of the `uptycscspm_role` and `uptycscspm_org_integration` roles allowed to read the logs",
+				Required:            true,
+				Type:                types.StringType,
+				PlanModifiers:       replaced,
+			},
+			"account_ids": {
+				MarkdownDescription: "Accounts whose integration role, named `integration_name`, may read the logs, such as the `ids` of " +
+					"`uptycscspm_org_accounts`. The bucket and key policies grant these roles only, and are updated in place when the list changes",
+				Required: true,
+				Type:     types.ListType{ElemType: types.StringType},
+			},
+			"kms_encryption": {
+				MarkdownDescription: "Encrypt the logs with a new KMS key. Integration roles outside of the management account " +
+					"also need `kms:Decrypt` on `kms_key_arn`, for example through a `statement` of `uptycscspm_policy_document`",
+				Optional:      true,
+				Type:          types.BoolType,
+				PlanModifiers: replaced,
+			},
+			"force_destroy": {
+				MarkdownDescription: "Delete every log in the bucket when the resource is destroyed. Otherwise destroying fails while the bucket is not empty",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"trail_arn": {
+				MarkdownDescription: "Trail ARN",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"kms_key_arn": {
+				MarkdownDescription: "ARN of the KMS key encrypting the logs, empty without `kms_encryption`",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"organization_id": {
+				MarkdownDescription: "Organization ID",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"prefix": {
+				MarkdownDescription: "Key prefix under which the logs of the organization's accounts are delivered, for the `bucket_prefix` of their integration roles",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       computed,
+			},
+			"drift": {
+				MarkdownDescription: "Changes made to the trail outside of Terraform, such as another log bucket, key prefix or KMS key. " +
+					"The trail is re-created when it is not empty",
+				Computed:      true,
+				Type:          types.StringType,
+				PlanModifiers: []tfsdk.AttributePlanModifier{driftModifier{}},
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"timeouts": timeoutsBlock(),
+		},
+	}, nil
+}
+
+func (t orgTrailResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return orgTrailResource{
+		provider: provider,
+	}, diags
+}
+
+type orgTrailResourceData struct {
+	ID              types.String   `tfsdk:"id"`
+	ProfileName     types.String   `tfsdk:"profile_name"`
+	TrailName       types.String   `tfsdk:"trail_name"`
+	BucketName      types.String   `tfsdk:"bucket_name"`
+	BucketRegion    types.String   `tfsdk:"bucket_region"`
+	S3KeyPrefix     types.String   `tfsdk:"s3_key_prefix"`
+	IntegrationName types.String   `tfsdk:"integration_name"`
+	AccountIDs      []string       `tfsdk:"account_ids"`
+	KMSEncryption   types.Bool     `tfsdk:"kms_encryption"`
+	ForceDestroy    types.Bool     `tfsdk:"force_destroy"`
+	TrailArn        types.String   `tfsdk:"trail_arn"`
+	KMSKeyArn       types.String   `tfsdk:"kms_key_arn"`
+	OrganizationID  types.String   `tfsdk:"organization_id"`
+	Prefix          types.String   `tfsdk:"prefix"`
+	Drift           types.String   `tfsdk:"drift"`
+	Timeouts        []timeoutsData `tfsdk:"timeouts"`
+}
+
+func (d orgTrailResourceData) spec() awsinternal.OrgTrailSpec {
+	return awsinternal.OrgTrailSpec{
+		ProfileName:      d.ProfileName.Value,
+		TrailName:        d.TrailName.Value,
+		BucketName:       d.BucketName.Value,
+		BucketRegion:     d.BucketRegion.Value,
+		S3KeyPrefix:      d.S3KeyPrefix.Value,
+		IntegrationName:  d.IntegrationName.Value,
+		ReaderAccountIDs: d.AccountIDs,
+		KMSEncryption:    d.KMSEncryption.Value,
+	}
+}
+
+// checkReaderAccounts reports an empty account_ids, which would leave the
+// logs readable by no integration role.
+func checkReaderAccounts(data orgTrailResourceData, diags *diag.Diagnostics) bool {
+	if len(data.AccountIDs) > 0 {
+		return true
+	}
+	diags.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("account_ids"), "Missing Reader Accounts",
+		"account_ids must list at least one account whose integration role reads the logs.")
+	return false
+}
+
+type orgTrailResource struct {
+	provider provider
+}
+
+func (r orgTrailResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var data orgTrailResourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	if !checkReaderAccounts(data, &resp.Diagnostics) {
+		return
+	}
+	trail, err := awsinternal.CreateOrgTrail(ctx, data.spec())
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to create organization trail %s", data.TrailName.Value), err)
+		return
+	}
+	data.ID = types.String{Value: trail.TrailArn}
+	data.TrailArn = types.String{Value: trail.TrailArn}
+	data.KMSKeyArn = types.String{Value: trail.KMSKeyArn}
+	data.OrganizationID = types.String{Value: trail.OrganizationID}
+	data.Prefix = types.String{Value: trail.LogPrefix()}
+	data.Drift = types.String{Value: ""}
+
+	tflog.Trace(ctx, "created an organization trail")
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r orgTrailResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var data orgTrailResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	trail, err := awsinternal.GetOrgTrail(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.TrailName.Value)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get organization trail %s", data.TrailName.Value), err)
+		return
+	}
+	if trail == nil {
+		// The trail was deleted outside of Terraform, plan to re-create it.
+		tflog.Warn(ctx, "organization trail not found, removing from state", map[string]interface{}{
+			"trail_name": data.TrailName.Value,
+		})
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	// The bucket and key in state are the ones this resource created and
+	// deletes, a trail changed to use others is re-created through drift.
+	created := awsinternal.OrgTrail{
+		BucketName:  data.BucketName.Value,
+		S3KeyPrefix: data.S3KeyPrefix.Value,
+		KMSKeyArn:   data.KMSKeyArn.Value,
+	}
+	drift := created.Drift(trail)
+	if len(drift) > 0 {
+		tflog.Warn(ctx, "organization trail changed outside of Terraform", map[string]interface{}{
+			"trail_name": data.TrailName.Value,
+			"drift":      drift,
+		})
+	}
+	data.TrailArn = types.String{Value: trail.TrailArn}
+	data.Drift = types.String{Value: strings.Join(drift, "; ")}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r orgTrailResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var data, prior orgTrailResourceData
+
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &prior)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	// Every other attribute requires replacement, only account_ids,
+	// force_destroy and the timeouts change in place.
+	if !reflect.DeepEqual(data.AccountIDs, prior.AccountIDs) {
+		if !checkReaderAccounts(data, &resp.Diagnostics) {
+			return
+		}
+		err := awsinternal.UpdateOrgTrailReaders(ctx, data.spec(), awsinternal.OrgTrail{
+			BucketName:     data.BucketName.Value,
+			KMSKeyArn:      data.KMSKeyArn.Value,
+			OrganizationID: data.OrganizationID.Value,
+		})
+		if err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to update the readers of organization trail %s", data.TrailName.Value), err)
+			return
+		}
+	}
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r orgTrailResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var data orgTrailResourceData
+
+	diags := req.State.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
+	err := awsinternal.DeleteOrgTrail(ctx, data.ProfileName.Value, awsinternal.OrgTrail{
+		TrailArn:     data.TrailArn.Value,
+		BucketName:   data.BucketName.Value,
+		BucketRegion: data.BucketRegion.Value,
+		KMSKeyArn:    data.KMSKeyArn.Value,
+	}, data.ForceDestroy.Value)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to delete organization trail %s", data.TrailName.Value), err)
+		return
+	}
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestOrgTrailSpec(t *testing.T) {
+	data := orgTrailResourceData{
+		ProfileName:     types.String{Value: "management"},
+		TrailName:       types.String{Value: "uptycs-org-trail"},
+		BucketName:      types.String{Value: "example-log-archive"},
+		BucketRegion:    types.String{Value: "us-east-1"},
+		S3KeyPrefix:     types.String{Null: true},
+		IntegrationName: types.String{Value: "uptcloud"},
+		AccountIDs:      []string{"123456789012", "234567890123"},
+		KMSEncryption:   types.Bool{Value: true},
+	}
+	expected := awsinternal.OrgTrailSpec{
+		ProfileName:      "management",
+		TrailName:        "uptycs-org-trail",
+		BucketName:       "example-log-archive",
+		BucketRegion:     "us-east-1",
+		IntegrationName:  "uptcloud",
+		ReaderAccountIDs: []string{"123456789012", "234567890123"},
+		KMSEncryption:    true,
+	}
+	if spec := data.spec(); !reflect.DeepEqual(spec, expected) {
+		t.Errorf("expected %+v, got %+v", expected, spec)
+	}
+}
+
+func TestCheckReaderAccounts(t *testing.T) {
+	var diags diag.Diagnostics
+	if !checkReaderAccounts(orgTrailResourceData{AccountIDs: []string{"123456789012"}}, &diags) || diags.HasError() {
+		t.Errorf("expected listed accounts to be accepted, got %v", diags)
+	}
+	for _, accountIDs := range [][]string{nil, {}} {
+		var diags diag.Diagnostics
+		if checkReaderAccounts(orgTrailResourceData{AccountIDs: accountIDs}, &diags) || !diags.HasError() {
+			t.Errorf("expected an error without accounts %v", accountIDs)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// driftModifier plans the replacement of a resource when its computed drift
+// attribute, set by Read, describes a change made outside of Terraform that
+// cannot be undone in place. The configured attributes are left as they are
+// so that the drift never leaks into the values the provider acts on.
+type driftModifier struct {
+	// inPlace is the drift that Update repairs, for which an update is
+	// planned instead.
+	inPlace []string
+}
+
+func (m driftModifier) Description(ctx context.Context) string {
+	return "Re-creates the resource when drift was found"
+}
+
+func (m driftModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m driftModifier) Modify(ctx context.Context, req tfsdk.ModifyAttributePlanRequest, resp *tfsdk.ModifyAttributePlanResponse) {
+	if req.AttributeState == nil {
+		return
+	}
+	var state types.String
+	diags := tfsdk.ValueAs(ctx, req.AttributeState, &state)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() || state.Null || state.Unknown {
+		return
+	}
+	resp.AttributePlan = types.String{Value: ""}
+	if state.Value == "" {
+		return
+	}
+	for _, drift := range m.inPlace {
+		if state.Value == drift {
+			return
+		}
+	}
+	resp.RequiresReplace = true
+}
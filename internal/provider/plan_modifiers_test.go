@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestDriftModifier(t *testing.T) {
+	modifier := driftModifier{inPlace: []string{logNotificationsMissingConfiguration}}
+	for _, c := range []struct {
+		state           attr.Value
+		plan            attr.Value
+		requiresReplace bool
+	}{
+		// nothing to plan on create
+		{state: nil, plan: types.String{Unknown: true}},
+		{state: types.String{Value: ""}, plan: types.String{Value: ""}},
+		{state: types.String{Value: logNotificationsMissingConfiguration}, plan: types.String{Value: ""}},
+		{state: types.String{Value: "missing queue"}, plan: types.String{Value: ""}, requiresReplace: true},
+	} {
+		resp := &tfsdk.ModifyAttributePlanResponse{AttributePlan: types.String{Unknown: true}}
+		modifier.Modify(context.Background(), tfsdk.ModifyAttributePlanRequest{AttributeState: c.state}, resp)
+		if resp.Diagnostics.HasError() {
+			t.Fatalf("%v: %v", c.state, resp.Diagnostics)
+		}
+		if !resp.AttributePlan.Equal(c.plan) || resp.RequiresReplace != c.requiresReplace {
+			t.Errorf("%v: expected plan %v and replace %t, got %v and %t", c.state, c.plan, c.requiresReplace, resp.AttributePlan, resp.RequiresReplace)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.DataSourceType = policyDocumentDataSourceType{}
+var _ tfsdk.DataSource = policyDocumentDataSource{}
+
+type policyDocumentDataSourceType struct{}
+
+func (t policyDocumentDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	featureAttribute := func(description string) tfsdk.Attribute {
+		return tfsdk.Attribute{
+			MarkdownDescription: description,
+			Optional:            true,
+			Type:                types.BoolType,
+		}
+	}
+	return tfsdk.Schema{
+		MarkdownDescription: "Renders the Uptycs ReadOnly Policy for the `policy_document` of `uptycscspm_role`",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "SHA-256 of the rendered policy",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"cspm":                        featureAttribute("Include the permissions for cloud security posture management. Defaults to `true`"),
+			"ciem":                        featureAttribute("Include the permissions for cloud infrastructure entitlement management"),
+			"dspm":                        featureAttribute("Include the permissions for data security posture management"),
+			"agentless_scanning":          featureAttribute("Include the permissions for agentless scanning of EBS snapshots"),
+			"container_registry_scanning": featureAttribute("Include the permissions for container registry scanning"),
+			"statement": {
+				MarkdownDescription: "Extra statements appended to the policy",
+				Optional:            true,
+				Attributes: tfsdk.ListNestedAttributes(map[string]tfsdk.Attribute{
+					"sid": {
+						MarkdownDescription: "Statement ID, unique among the statements of the policy, including those of the enabled features",
+						Optional:            true,
+						Type:                types.StringType,
+					},
+					"effect": {
+						MarkdownDescription: "`Allow` or `Deny`. Defaults to `Allow`",
+						Optional:            true,
+						Type:                types.StringType,
+						Validators:          []tfsdk.AttributeValidator{oneOfValidator{values: []string{"Allow", "Deny"}}},
+					},
+					"actions": {
+						MarkdownDescription: "Actions of the statement",
+						Required:            true,
+						Type:                types.ListType{ElemType: types.StringType},
+					},
+					"resources": {
+						MarkdownDescription: "Resources of the statement. Defaults to `*`",
+						Optional:            true,
+						Type:                types.ListType{ElemType: types.StringType},
+					},
+				}),
+			},
+			"log_queue_arns": {
+				MarkdownDescription: "Log notification queues the role consumes, such as the `queue_arn` of `uptycscspm_log_notifications`",
+				Optional:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"json": {
+				MarkdownDescription: "Rendered policy, as canonical JSON",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"size": {
+				MarkdownDescription: "Size of the rendered policy as counted by IAM",
+				Computed:            true,
+				Type:                types.Int64Type,
+			},
+		},
+	}, nil
+}
+
+func (t policyDocumentDataSourceType) NewDataSource(ctx context.Context, in tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return policyDocumentDataSource{
+		provider: provider,
+	}, diags
+}
+
+type policyDocumentDataSourceData struct {
+	ID                        types.String          `tfsdk:"id"`
+	CSPM                      types.Bool            `tfsdk:"cspm"`
+	CIEM                      types.Bool            `tfsdk:"ciem"`
+	DSPM                      types.Bool            `tfsdk:"dspm"`
+	AgentlessScanning         types.Bool            `tfsdk:"agentless_scanning"`
+	ContainerRegistryScanning types.Bool            `tfsdk:"container_registry_scanning"`
+	Statement                 []policyStatementData `tfsdk:"statement"`
+	LogQueueArns              []string              `tfsdk:"log_queue_arns"`
+	JSON                      types.String          `tfsdk:"json"`
+	Size                      types.Int64           `tfsdk:"size"`
+}
+
+type policyStatementData struct {
+	Sid       types.String `tfsdk:"sid"`
+	Effect    types.String `tfsdk:"effect"`
+	Actions   []string     `tfsdk:"actions"`
+	Resources []string     `tfsdk:"resources"`
+}
+
+// policy renders the policy of the features enabled in d, followed by the
+// log queue statement and the extra statements. Statements default to
+// allowing their actions on all resources.
+func (d policyDocumentDataSourceData) policy() (*awsinternal.PolicyDocument, error) {
+	features := map[string]bool{
+		awsinternal.FeatureCSPM:                      d.CSPM.Null || d.CSPM.Value,
+		awsinternal.FeatureCIEM:                      d.CIEM.Value,
+		awsinternal.FeatureDSPM:                      d.DSPM.Value,
+		awsinternal.FeatureAgentlessScanning:         d.AgentlessScanning.Value,
+		awsinternal.FeatureContainerRegistryScanning: d.ContainerRegistryScanning.Value,
+	}
+	var extra []awsinternal.PolicyStatement
+	if len(d.LogQueueArns) > 0 {
+		extra = append(extra, awsinternal.LogQueueStatement(d.LogQueueArns))
+	}
+	for _, statement := range d.Statement {
+		effect := "Allow"
+		if !statement.Effect.Null && statement.Effect.Value != "" {
+			effect = statement.Effect.Value
+		}
+		resources := statement.Resources
+		if len(resources) == 0 {
+			resources = []string{"*"}
+		}
+		extra = append(extra, awsinternal.PolicyStatement{
+			Sid:      statement.Sid.Value,
+			Effect:   effect,
+			Action:   statement.Actions,
+			Resource: resources,
+		})
+	}
+	return awsinternal.BuildReadOnlyPolicy(features, extra)
+}
+
+type policyDocumentDataSource struct {
+	provider provider
+}
+
+func (d policyDocumentDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var data policyDocumentDataSourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	policy, err := data.policy()
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Policy", fmt.Sprintf("Unable to render the Uptycs ReadOnly Policy. err=%s", err))
+		return
+	}
+	document, err := policy.Canonical()
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Policy", fmt.Sprintf("Unable to render the Uptycs ReadOnly Policy. err=%s", err))
+		return
+	}
+	if err := awsinternal.CheckInlinePolicySize(document); err != nil {
+		resp.Diagnostics.AddError("Policy Too Large", fmt.Sprintf("%s. Disable features or remove extra statements.", err))
+		return
+	}
+
+	data.ID = types.String{Value: fmt.Sprintf("%x", sha256.Sum256([]byte(document)))}
+	data.JSON = types.String{Value: document}
+	data.Size = types.Int64{Value: int64(awsinternal.PolicySize(document))}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
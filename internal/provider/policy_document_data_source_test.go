@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestAccPolicyDocumentDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPolicyDocumentDataSourceConfig,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("data.uptycscspm_policy_document.test", "json", regexp.MustCompile(`"Sid":"UptycsCspmReadOnly"`)),
+					resource.TestMatchResourceAttr("data.uptycscspm_policy_document.test", "json", regexp.MustCompile(`"Sid":"UptycsContainerRegistryReadOnly"`)),
+					resource.TestMatchResourceAttr("data.uptycscspm_policy_document.test", "json", regexp.MustCompile(`"Action":\["kms:Decrypt","s3:GetObject"\]`)),
+					resource.TestMatchResourceAttr("data.uptycscspm_policy_document.test", "json", regexp.MustCompile(`"Sid":"UptycsLogNotifications"`)),
+					resource.TestCheckResourceAttrSet("data.uptycscspm_policy_document.test", "size"),
+				),
+			},
+		},
+	})
+}
+
+func TestPolicyDocumentPolicy(t *testing.T) {
+	data := policyDocumentDataSourceData{
+		CSPM:                      types.Bool{Null: true},
+		ContainerRegistryScanning: types.Bool{Value: true},
+		LogQueueArns:              []string{"arn:aws:sqs:us-east-1:234567890123:uptycs-cloudtrail"},
+		Statement: []policyStatementData{
+			{Sid: types.String{Value: "Extra"}, Effect: types.String{Null: true}, Actions: []string{"s3:GetObject", "kms:Decrypt"}},
+			{Sid: types.String{Null: true}, Effect: types.String{Value: "Deny"}, Actions: []string{"s3:DeleteObject"}, Resources: []string{"arn:aws:s3:::example/*"}},
+		},
+	}
+	policy, err := data.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sids []string
+	for _, statement := range policy.Statement {
+		sids = append(sids, statement.Sid)
+	}
+	expected := []string{"UptycsCspmReadOnly", "UptycsContainerRegistryReadOnly", "UptycsLogNotifications", "Extra", ""}
+	if !reflect.DeepEqual(sids, expected) {
+		t.Fatalf("expected statements %v, got %v", expected, sids)
+	}
+	extra := policy.Statement[3]
+	if extra.Effect != "Allow" || !reflect.DeepEqual(extra.Resource, awsinternal.StringList{"*"}) {
+		t.Errorf("expected the statement to allow all resources by default, got %+v", extra)
+	}
+	deny := policy.Statement[4]
+	if deny.Effect != "Deny" || !reflect.DeepEqual(deny.Resource, awsinternal.StringList{"arn:aws:s3:::example/*"}) {
+		t.Errorf("expected the configured effect and resources, got %+v", deny)
+	}
+
+	if _, err := (policyDocumentDataSourceData{CSPM: types.Bool{Value: false}}).policy(); err == nil {
+		t.Error("expected an error for a policy without statements")
+	}
+	data.Statement[0].Sid = types.String{Value: "UptycsCspmReadOnly"}
+	if _, err := data.policy(); err == nil {
+		t.Error("expected an error for a statement ID of the catalog")
+	}
+}
+
+const testAccPolicyDocumentDataSourceConfig = `
+data "uptycscspm_policy_document" "test" {
+  container_registry_scanning = true
+  log_queue_arns = ["arn:aws:sqs:us-east-1:234567890123:uptycs-cloudtrail"]
+  statement = [
+    {
+      sid = "Extra"
+      actions = ["s3:GetObject", "kms:Decrypt"]
+    },
+  ]
+}
+`
@@ -3,9 +3,14 @@ package provider
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+	"github.com/uptycslabs/terraform-provider-uptycscspm/internal/uptycs"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces
@@ -30,10 +35,32 @@ type provider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	// orgInventory lists organization accounts once per Terraform run and is
+	// shared by every org-aware resource and data source.
+	orgInventory *awsinternal.OrgInventory
+
+	// uptycs calls the API of the Uptycs tenant. It is nil when the
+	// provider is not configured with an API key.
+	uptycs *uptycs.Client
 }
 
 // providerData can be used to store data from the Terraform configuration.
 type providerData struct {
+	OrgRoleArn       types.String `tfsdk:"org_role_arn"`
+	UptycsHost       types.String `tfsdk:"uptycs_host"`
+	UptycsAPIKey     types.String `tfsdk:"uptycs_api_key"`
+	UptycsAPISecret  types.String `tfsdk:"uptycs_api_secret"`
+	UptycsCustomerID types.String `tfsdk:"uptycs_customer_id"`
+}
+
+// configValue returns the value of an attribute of the provider block, or
+// of the environment variable when the attribute is not set.
+func configValue(value types.String, envVar string) string {
+	if !value.Null && !value.Unknown && value.Value != "" {
+		return value.Value
+	}
+	return os.Getenv(envVar)
 }
 
 func (p *provider) Configure(ctx context.Context, req tfsdk.ConfigureProviderRequest, resp *tfsdk.ConfigureProviderResponse) {
@@ -46,34 +73,105 @@ func (p *provider) Configure(ctx context.Context, req tfsdk.ConfigureProviderReq
 	}
 
 	// Configuration values are now available.
-	// if data.Example.Null { /* ... */ }
+	if !data.OrgRoleArn.Null && data.OrgRoleArn.Value != "" {
+		p.orgInventory.SetRoleArn(data.OrgRoleArn.Value)
+	}
 
-	// If the upstream provider SDK or HTTP client requires configuration, such
-	// as authentication or logging, this is a great opportunity to do so.
+	cfg := uptycs.Config{
+		Host:       configValue(data.UptycsHost, "UPTYCS_HOST"),
+		APIKey:     configValue(data.UptycsAPIKey, "UPTYCS_API_KEY"),
+		APISecret:  configValue(data.UptycsAPISecret, "UPTYCS_API_SECRET"),
+		CustomerID: configValue(data.UptycsCustomerID, "UPTYCS_CUSTOMER_ID"),
+	}
+	// The Uptycs API is only needed by the resources registering with the
+	// tenant, the provider works with AWS alone when none of it is set.
+	if cfg.Host != "" || cfg.APIKey != "" || cfg.APISecret != "" || cfg.CustomerID != "" {
+		client, err := uptycs.NewClient(cfg)
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid Uptycs Configuration",
+				fmt.Sprintf("Unable to create Uptycs API client. err=%s\n\nSet uptycs_host, uptycs_api_key, uptycs_api_secret "+
+					"and uptycs_customer_id in the provider block or the matching UPTYCS_ environment variables.", err))
+			return
+		}
+		p.uptycs = client
+	}
 
 	p.configured = true
 }
 
+// uptycsClient returns the client of the Uptycs API, or adds an error to
+// diags when the provider is not configured with an API key.
+func (p provider) uptycsClient(diags *diag.Diagnostics) *uptycs.Client {
+	if p.uptycs == nil {
+		diags.AddError("Uptycs API Not Configured",
+			"Set uptycs_host, uptycs_api_key, uptycs_api_secret and uptycs_customer_id in the provider block "+
+				"or the matching UPTYCS_ environment variables to manage the Uptycs tenant.")
+	}
+	return p.uptycs
+}
+
 func (p *provider) GetResources(ctx context.Context) (map[string]tfsdk.ResourceType, diag.Diagnostics) {
 	return map[string]tfsdk.ResourceType{
-		"uptycscspm_role": roleResourceType{},
+		"uptycscspm_aws_integration":     awsIntegrationResourceType{},
+		"uptycscspm_bucket_policy_grant": bucketPolicyGrantResourceType{},
+		"uptycscspm_event_forwarding":    eventForwardingResourceType{},
+		"uptycscspm_log_notifications":   logNotificationsResourceType{},
+		"uptycscspm_org_integration":     orgIntegrationResourceType{},
+		"uptycscspm_org_trail":           orgTrailResourceType{},
+		"uptycscspm_role":                roleResourceType{},
 	}, nil
 }
 
 func (p *provider) GetDataSources(ctx context.Context) (map[string]tfsdk.DataSourceType, diag.Diagnostics) {
-	return map[string]tfsdk.DataSourceType{}, nil
+	return map[string]tfsdk.DataSourceType{
+		"uptycscspm_org_accounts":     orgAccountsDataSourceType{},
+		"uptycscspm_policy_document":  policyDocumentDataSourceType{},
+		"uptycscspm_role":             roleDataSourceType{},
+		"uptycscspm_role_permissions": rolePermissionsDataSourceType{},
+		"uptycscspm_tenant_trust":     tenantTrustDataSourceType{},
+	}, nil
 }
 
 func (p *provider) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
 	return tfsdk.Schema{
-		Attributes: map[string]tfsdk.Attribute{},
+		Attributes: map[string]tfsdk.Attribute{
+			"org_role_arn": {
+				MarkdownDescription: "Role assumed with the credentials of `profile_name` to list the organization accounts, " +
+					"for example a role of a delegated administrator account. When the role is not allowed to call " +
+					"`organizations:ListAccounts`, the accounts are listed by walking the organization tree",
+				Optional: true,
+				Type:     types.StringType,
+			},
+			"uptycs_host": {
+				MarkdownDescription: "Hostname of the Uptycs tenant, such as `mytenant.uptycs.io`. Defaults to the `UPTYCS_HOST` environment variable",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"uptycs_api_key": {
+				MarkdownDescription: "Key of an API key of the Uptycs tenant. Defaults to the `UPTYCS_API_KEY` environment variable",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"uptycs_api_secret": {
+				MarkdownDescription: "Secret of the API key. Defaults to the `UPTYCS_API_SECRET` environment variable",
+				Optional:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+			"uptycs_customer_id": {
+				MarkdownDescription: "Customer ID of the Uptycs tenant. Defaults to the `UPTYCS_CUSTOMER_ID` environment variable",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+		},
 	}, nil
 }
 
 func New(version string) func() tfsdk.Provider {
 	return func() tfsdk.Provider {
 		return &provider{
-			version: version,
+			version:      version,
+			orgInventory: awsinternal.NewOrgInventory(),
 		}
 	}
 }
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.DataSourceType = roleDataSourceType{}
+var _ tfsdk.DataSource = roleDataSource{}
+
+type roleDataSourceType struct{}
+
+func (t roleDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Reads an existing Uptycs integration role",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Role ARN",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"integration_name": {
+				MarkdownDescription: "Integration name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"role": {
+				MarkdownDescription: "Role ARN",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"trust_principals": {
+				MarkdownDescription: "AWS principals trusted to assume the role",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"external_ids": {
+				MarkdownDescription: "External IDs required by the `sts:ExternalId` condition of the trust policy",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"policy_document": {
+				MarkdownDescription: "Uptycs ReadOnly Policy attached inline to the role",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"managed_policy_arns": {
+				MarkdownDescription: "ARNs of the managed policies attached to the role",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"bucket_name": {
+				MarkdownDescription: "Cloudtrail Bucket the role can read, empty when none is configured",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"bucket_prefix": {
+				MarkdownDescription: "Key prefix of the logs the role can read in `bucket_name`, empty for the whole bucket",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+		},
+	}, nil
+}
+
+func (t roleDataSourceType) NewDataSource(ctx context.Context, in tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return roleDataSource{
+		provider: provider,
+	}, diags
+}
+
+type roleDataSourceData struct {
+	ID                   types.String `tfsdk:"id"`
+	ProfileName          types.String `tfsdk:"profile_name"`
+	AccountID            types.String `tfsdk:"account_id"`
+	IntegrationName      types.String `tfsdk:"integration_name"`
+	OrgAccessRoleName    types.String `tfsdk:"org_access_role_name"`
+	UseCallerCredentials types.Bool   `tfsdk:"use_caller_credentials"`
+	Role                 types.String `tfsdk:"role"`
+	TrustPrincipals      []string     `tfsdk:"trust_principals"`
+	ExternalIDs          []string     `tfsdk:"external_ids"`
+	PolicyDocument       types.String `tfsdk:"policy_document"`
+	ManagedPolicyArns    []string     `tfsdk:"managed_policy_arns"`
+	BucketName           types.String `tfsdk:"bucket_name"`
+	BucketPrefix         types.String `tfsdk:"bucket_prefix"`
+}
+
+// setRole sets the computed attributes of d to the description of the role.
+func (d *roleDataSourceData) setRole(role *awsinternal.RoleDescription) {
+	d.ID = types.String{Value: role.Arn}
+	d.Role = types.String{Value: role.Arn}
+	d.TrustPrincipals = role.TrustPrincipals
+	d.ExternalIDs = role.ExternalIDs
+	d.PolicyDocument = types.String{Value: role.InlinePolicyDocument}
+	d.ManagedPolicyArns = make([]string, 0, len(role.AttachedPolicyArns))
+	for arn := range role.AttachedPolicyArns {
+		d.ManagedPolicyArns = append(d.ManagedPolicyArns, arn)
+	}
+	sort.Strings(d.ManagedPolicyArns)
+	d.BucketName = types.String{Value: role.BucketName}
+	d.BucketPrefix = types.String{Value: role.BucketPrefix}
+}
+
+type roleDataSource struct {
+	provider provider
+}
+
+func (d roleDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var data roleDataSourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, nil, "read", defaultReadTimeout)
+	defer cancel()
+
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if errSvc != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
+		return
+	}
+	role, errRole := awsinternal.DescribeIntegrationRole(ctx, svc, data.AccountID.Value, data.IntegrationName.Value)
+	if errRole != nil {
+		addAwsError(&resp.Diagnostics, "Unable to read uptycscspm role", errRole)
+		return
+	}
+
+	data.setRole(role)
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
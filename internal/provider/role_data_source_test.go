@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestRoleDataSourceSetRole(t *testing.T) {
+	var data roleDataSourceData
+	data.setRole(&awsinternal.RoleDescription{
+		RoleStatus: awsinternal.RoleStatus{
+			Arn:          "arn:aws:iam::123456789012:role/uptcloud",
+			InlinePolicy: true,
+			AttachedPolicyArns: map[string]bool{
+				awsinternal.ViewOnlyAccessArn:                                        true,
+				awsinternal.SecurityAuditArn:                                         true,
+				awsinternal.GetCloudtrailBucketPolicyArn("123456789012", "uptcloud"): true,
+			},
+		},
+		TrustPrincipals:      []string{"arn:aws:iam::012345678912:root"},
+		ExternalIDs:          []string{"6a9375c1-47c0-470c-9217-d2f9d2d185f1"},
+		InlinePolicyDocument: `{"Version":"2012-10-17","Statement":[]}`,
+		BucketName:           "example-log-archive",
+		BucketPrefix:         "AWSLogs/",
+	})
+
+	if data.ID.Value != "arn:aws:iam::123456789012:role/uptcloud" || data.Role.Value != data.ID.Value {
+		t.Errorf("unexpected role %s and id %s", data.Role.Value, data.ID.Value)
+	}
+	expected := []string{
+		"arn:aws:iam::123456789012:policy/uptcloud-CloudtrailBucketPolicy",
+		awsinternal.SecurityAuditArn,
+		awsinternal.ViewOnlyAccessArn,
+	}
+	if !reflect.DeepEqual(data.ManagedPolicyArns, expected) {
+		t.Errorf("expected sorted managed policies %v, got %v", expected, data.ManagedPolicyArns)
+	}
+	if data.BucketName.Value != "example-log-archive" || data.BucketPrefix.Value != "AWSLogs/" {
+		t.Errorf("unexpected bucket %s and prefix %s", data.BucketName.Value, data.BucketPrefix.Value)
+	}
+
+	data.setRole(&awsinternal.RoleDescription{RoleStatus: awsinternal.RoleStatus{Arn: "arn:aws:iam::123456789012:role/uptcloud"}})
+	if data.ManagedPolicyArns == nil || len(data.ManagedPolicyArns) != 0 {
+		t.Errorf("expected an empty list of managed policies, got %v", data.ManagedPolicyArns)
+	}
+}
@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.DataSourceType = rolePermissionsDataSourceType{}
+var _ tfsdk.DataSource = rolePermissionsDataSource{}
+
+type rolePermissionsDataSourceType struct{}
+
+func (t rolePermissionsDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Checks with the IAM policy simulator which actions Uptycs requires an existing integration role is not allowed",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "Role ARN",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"profile_name": {
+				MarkdownDescription: "Profile name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"integration_name": {
+				MarkdownDescription: "Integration name",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"org_access_role_name": {
+				MarkdownDescription: "Organization Account Access Role Name",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"policy_document": {
+				MarkdownDescription: "Uptycs ReadOnly Policy whose actions are required too, such as the `json` of `uptycscspm_policy_document`",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"required_actions": {
+				MarkdownDescription: "Actions simulated for the role",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+			"missing_permissions": {
+				MarkdownDescription: "Required actions the role is not allowed",
+				Computed:            true,
+				Type:                types.ListType{ElemType: types.StringType},
+			},
+		},
+	}, nil
+}
+
+func (t rolePermissionsDataSourceType) NewDataSource(ctx context.Context, in tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return rolePermissionsDataSource{
+		provider: provider,
+	}, diags
+}
+
+type rolePermissionsDataSourceData struct {
+	ID                   types.String `tfsdk:"id"`
+	ProfileName          types.String `tfsdk:"profile_name"`
+	AccountID            types.String `tfsdk:"account_id"`
+	IntegrationName      types.String `tfsdk:"integration_name"`
+	OrgAccessRoleName    types.String `tfsdk:"org_access_role_name"`
+	UseCallerCredentials types.Bool   `tfsdk:"use_caller_credentials"`
+	PolicyDocument       types.String `tfsdk:"policy_document"`
+	RequiredActions      []string     `tfsdk:"required_actions"`
+	MissingPermissions   []string     `tfsdk:"missing_permissions"`
+}
+
+type rolePermissionsDataSource struct {
+	provider provider
+}
+
+func (d rolePermissionsDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var data rolePermissionsDataSourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, nil, "read", defaultReadTimeout)
+	defer cancel()
+
+	actions, err := awsinternal.RequiredActions(data.PolicyDocument.Value)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Policy Document", fmt.Sprintf("Unable to list the actions of policy_document. err=%s", err))
+		return
+	}
+
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if errSvc != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
+		return
+	}
+	roleArn, err := awsinternal.GetIntegrationRoleName(ctx, svc, data.IntegrationName.Value)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, "Unable to read uptycscspm role", err)
+		return
+	}
+	missing, err := awsinternal.SimulateRolePermissions(ctx, svc, roleArn, actions)
+	if err != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to simulate the permissions of role %s", data.IntegrationName.Value), err)
+		return
+	}
+
+	data.ID = types.String{Value: roleArn}
+	data.RequiredActions = actions
+	data.MissingPermissions = missing
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	awsinternal "github.com/uptycslabs/terraform-provider-uptycscspm/internal/aws"
+)
+
+func TestRequiredActionsOfPolicyDocument(t *testing.T) {
+	data := policyDocumentDataSourceData{
+		CSPM:              types.Bool{Null: true},
+		AgentlessScanning: types.Bool{Value: true},
+	}
+	policy, err := data.policy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := policy.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions, err := awsinternal.RequiredActions(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	required := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		required[action] = true
+	}
+	for _, action := range []string{"ec2:CreateSnapshot", "ebs:ListSnapshotBlocks", "eks:ListClusters"} {
+		if !required[action] {
+			t.Errorf("expected %s to be required", action)
+		}
+	}
+	// Only the actions granted on every resource without conditions are
+	// simulated.
+	for _, action := range []string{"ec2:CopySnapshot", "ec2:ModifySnapshotAttribute", "ec2:DeleteSnapshot", "kms:CreateGrant", "kms:Decrypt"} {
+		if required[action] {
+			t.Errorf("expected %s not to be required", action)
+		}
+	}
+}
@@ -3,7 +3,10 @@ package provider
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -17,6 +20,14 @@ import (
 var _ tfsdk.ResourceType = roleResourceType{}
 var _ tfsdk.Resource = roleResource{}
 var _ tfsdk.ResourceWithImportState = roleResource{}
+var _ tfsdk.ResourceWithModifyPlan = roleResource{}
+
+// Values of permission_check.
+const (
+	permissionCheckWarning = "warning"
+	permissionCheckError   = "error"
+	permissionCheckOff     = "off"
+)
 
 type roleResourceType struct{}
 
@@ -43,13 +54,47 @@ func (t roleResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Dia
 				Type:                types.StringType,
 			},
 			"upt_account_id": {
-				MarkdownDescription: "Uptycs AWS account ID",
-				Required:            true,
+				MarkdownDescription: "Uptycs AWS account ID. Required unless the `trust` block has `account_ids` or `principal_arns`",
+				Optional:            true,
 				Type:                types.StringType,
 			},
 			"external_id": {
-				MarkdownDescription: "External ID",
-				Required:            true,
+				MarkdownDescription: "External ID Uptycs must pass to assume the role. Generated when not set, as a random UUID that " +
+					"is kept until `rotate_external_id` changes",
+				Optional:      true,
+				Computed:      true,
+				Sensitive:     true,
+				Type:          types.StringType,
+				PlanModifiers: []tfsdk.AttributePlanModifier{tfsdk.UseStateForUnknown()},
+			},
+			"rotate_external_id": {
+				MarkdownDescription: "Any value, changing it generates a new `external_id`. Ignored when `external_id` is set",
+				Optional:            true,
+				Type:                types.StringType,
+			},
+			"rotation_grace_period": {
+				MarkdownDescription: "Duration, such as `72h`, the trust policy keeps accepting the previous `external_id` after it changes, " +
+					"so that Uptycs can assume the role until the tenant is updated. The first apply after it ends removes the previous " +
+					"external ID. When not set, the previous external ID is removed right away",
+				Optional:   true,
+				Type:       types.StringType,
+				Validators: []tfsdk.AttributeValidator{durationValidator{}},
+			},
+			"rotation_confirmed": {
+				MarkdownDescription: "Set to `true` once the tenant uses the new `external_id`, the next apply then removes the previous one " +
+					"before `rotation_grace_period` ends. Set it back to `false` before the next rotation",
+				Optional: true,
+				Type:     types.BoolType,
+			},
+			"previous_external_id": {
+				MarkdownDescription: "External ID the trust policy still accepts during a rotation, null otherwise",
+				Computed:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+			"previous_external_id_expires_at": {
+				MarkdownDescription: "RFC 3339 time after which the next apply removes `previous_external_id`, null when there is none",
+				Computed:            true,
 				Type:                types.StringType,
 			},
 			"role": {
@@ -58,15 +103,22 @@ func (t roleResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Dia
 				Type:                types.StringType,
 			},
 			"bucket_name": {
-				MarkdownDescription: "Cloudtrail Bucket",
-				Required:            true,
-				Type:                types.StringType,
+				MarkdownDescription: "Cloudtrail Bucket. Checked when planned: it must be in `bucket_region`, and logs encrypted with KMS or the lack of " +
+					"CloudTrail logs of the account under `bucket_prefix` are warned about",
+				Required: true,
+				Type:     types.StringType,
 			},
 			"bucket_region": {
 				MarkdownDescription: "Cloudtrail Bucket Region",
 				Required:            true,
 				Type:                types.StringType,
 			},
+			"bucket_prefix": {
+				MarkdownDescription: "Key prefix of the CloudTrail logs in the bucket, such as the `prefix` of `uptycscspm_org_trail`. " +
+					"The role can only read the logs under it. Defaults to the whole bucket",
+				Optional: true,
+				Type:     types.StringType,
+			},
 			"policy_document": {
 				MarkdownDescription: "Uptycs ReadOnly Policy",
 				Required:            true,
@@ -77,6 +129,98 @@ func (t roleResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Dia
 				Optional:            true,
 				Type:                types.StringType,
 			},
+			"standalone": {
+				MarkdownDescription: "Skip the AWS Organizations membership check for `account_id`. Use for standalone accounts or when `organizations:ListAccounts` is denied",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"use_caller_credentials": {
+				MarkdownDescription: "Use the credentials of `profile_name` directly instead of assuming `org_access_role_name`. The credentials must belong to `account_id`",
+				Optional:            true,
+				Type:                types.BoolType,
+			},
+			"verifier_profile_name": {
+				MarkdownDescription: "Profile with the credentials of a principal of `upt_account_id`, for example in a test setup. When set, " +
+					"the role is assumed with `external_id` after it is created and on every refresh, and must not be assumable without it",
+				Optional: true,
+				Type:     types.StringType,
+			},
+			"verification_status": {
+				MarkdownDescription: "`verified` when the verifier assumed the role, `policy_valid` when only the static checks of the trust policy " +
+					"ran and passed, or `failed`",
+				Computed: true,
+				Type:     types.StringType,
+			},
+			"verification_error": {
+				MarkdownDescription: "Check that failed when `verification_status` is `failed`, empty otherwise",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"permission_check": {
+				MarkdownDescription: "How actions Uptycs requires but the role is not allowed, according to the IAM policy simulator, are reported: " +
+					"`warning`, `error` or `off`. Defaults to `warning`. With `error`, the plan of an existing role fails while actions are " +
+					"missing; the apply creating or re-creating the role only warns, so that the role is kept in state",
+				Optional:   true,
+				Type:       types.StringType,
+				Validators: []tfsdk.AttributeValidator{oneOfValidator{values: []string{permissionCheckWarning, permissionCheckError, permissionCheckOff}}},
+			},
+			"drift": {
+				MarkdownDescription: "Policies of the role deleted or detached outside of Terraform. The role is re-created when it is not empty",
+				Computed:            true,
+				Type:                types.StringType,
+				PlanModifiers:       []tfsdk.AttributePlanModifier{driftModifier{}},
+			},
+			"missing_permissions": {
+				MarkdownDescription: "Actions Uptycs requires that the role is not allowed, for example because of a permissions boundary or a " +
+					"service control policy. Null when `permission_check` is `off` or the simulation failed",
+				Computed: true,
+				Type:     types.ListType{ElemType: types.StringType},
+			},
+		},
+		Blocks: map[string]tfsdk.Block{
+			"trust": {
+				MarkdownDescription: "Uptycs principals the trust policy lets assume the role, and the conditions they must meet on top of " +
+					"`external_id`. When not set, or without `account_ids` nor `principal_arns`, `upt_account_id` is trusted",
+				NestingMode: tfsdk.BlockNestingModeList,
+				MaxItems:    1,
+				Attributes: map[string]tfsdk.Attribute{
+					"account_ids": {
+						MarkdownDescription: "Uptycs accounts trusted through their root principal, for example several tenant accounts during a migration",
+						Optional:            true,
+						Type:                types.ListType{ElemType: types.StringType},
+					},
+					"principal_arns": {
+						MarkdownDescription: "Uptycs principals trusted on their own, such as the `upt_principal_arn` of `uptycscspm_tenant_trust`",
+						Optional:            true,
+						Type:                types.ListType{ElemType: types.StringType},
+					},
+				},
+				Blocks: map[string]tfsdk.Block{
+					"condition": {
+						MarkdownDescription: "Condition of the trust policy, such as the `IpAddress` test of `aws:SourceIp` or the `StringEquals` " +
+							"test of `aws:PrincipalOrgID`",
+						NestingMode: tfsdk.BlockNestingModeList,
+						Attributes: map[string]tfsdk.Attribute{
+							"test": {
+								MarkdownDescription: "Condition operator, such as `StringEquals` or `IpAddress`",
+								Required:            true,
+								Type:                types.StringType,
+							},
+							"variable": {
+								MarkdownDescription: "Condition key, such as `aws:SourceIp`",
+								Required:            true,
+								Type:                types.StringType,
+							},
+							"values": {
+								MarkdownDescription: "Values of the key, any of which meets the condition",
+								Required:            true,
+								Type:                types.ListType{ElemType: types.StringType},
+							},
+						},
+					},
+				},
+			},
+			"timeouts": timeoutsBlock(),
 		},
 	}, nil
 }
@@ -91,32 +235,363 @@ func (t roleResourceType) NewResource(ctx context.Context, in tfsdk.Provider) (t
 }
 
 type exampleResourceData struct {
-	ProfileName       types.String `tfsdk:"profile_name"`
-	AccountID         types.String `tfsdk:"account_id"`
-	IntegrationName   types.String `tfsdk:"integration_name"`
-	UptAccountID      types.String `tfsdk:"upt_account_id"`
-	ExternalID        types.String `tfsdk:"external_id"`
-	Role              types.String `tfsdk:"role"`
-	BucketName        types.String `tfsdk:"bucket_name"`
-	BucketRegion      types.String `tfsdk:"bucket_region"`
-	PolicyDocument    types.String `tfsdk:"policy_document"`
-	OrgAccessRoleName types.String `tfsdk:"org_access_role_name"`
+	ProfileName          types.String    `tfsdk:"profile_name"`
+	AccountID            types.String    `tfsdk:"account_id"`
+	IntegrationName      types.String    `tfsdk:"integration_name"`
+	UptAccountID         types.String    `tfsdk:"upt_account_id"`
+	ExternalID           types.String    `tfsdk:"external_id"`
+	RotateExternalID     types.String    `tfsdk:"rotate_external_id"`
+	RotationGracePeriod  types.String    `tfsdk:"rotation_grace_period"`
+	RotationConfirmed    types.Bool      `tfsdk:"rotation_confirmed"`
+	PreviousExternalID   types.String    `tfsdk:"previous_external_id"`
+	PreviousExpiresAt    types.String    `tfsdk:"previous_external_id_expires_at"`
+	Role                 types.String    `tfsdk:"role"`
+	BucketName           types.String    `tfsdk:"bucket_name"`
+	BucketRegion         types.String    `tfsdk:"bucket_region"`
+	BucketPrefix         types.String    `tfsdk:"bucket_prefix"`
+	PolicyDocument       types.String    `tfsdk:"policy_document"`
+	OrgAccessRoleName    types.String    `tfsdk:"org_access_role_name"`
+	Standalone           types.Bool      `tfsdk:"standalone"`
+	UseCallerCredentials types.Bool      `tfsdk:"use_caller_credentials"`
+	VerifierProfileName  types.String    `tfsdk:"verifier_profile_name"`
+	VerificationStatus   types.String    `tfsdk:"verification_status"`
+	VerificationError    types.String    `tfsdk:"verification_error"`
+	PermissionCheck      types.String    `tfsdk:"permission_check"`
+	MissingPermissions   types.List      `tfsdk:"missing_permissions"`
+	Drift                types.String    `tfsdk:"drift"`
+	Trust                []roleTrustData `tfsdk:"trust"`
+	Timeouts             []timeoutsData  `tfsdk:"timeouts"`
+}
+
+type roleTrustData struct {
+	AccountIDs    types.List               `tfsdk:"account_ids"`
+	PrincipalArns types.List               `tfsdk:"principal_arns"`
+	Conditions    []roleTrustConditionData `tfsdk:"condition"`
+}
+
+type roleTrustConditionData struct {
+	Test     types.String `tfsdk:"test"`
+	Variable types.String `tfsdk:"variable"`
+	Values   types.List   `tfsdk:"values"`
+}
+
+// roleChanged reports whether other needs the role to be re-created rather
+// than its trust policy to be updated.
+func (d exampleResourceData) roleChanged(other exampleResourceData) bool {
+	return d.AccountID.Value != other.AccountID.Value ||
+		d.IntegrationName.Value != other.IntegrationName.Value ||
+		d.BucketName.Value != other.BucketName.Value ||
+		d.BucketRegion.Value != other.BucketRegion.Value ||
+		d.BucketPrefix.Value != other.BucketPrefix.Value ||
+		d.PolicyDocument.Value != other.PolicyDocument.Value ||
+		d.OrgAccessRoleName.Value != other.OrgAccessRoleName.Value
+}
+
+// trust returns who the trust policy lets assume the role, the trust block
+// or upt_account_id, and the external IDs it accepts, the previous one last
+// during a rotation.
+func (d exampleResourceData) trust() awsinternal.Trust {
+	trust := awsinternal.Trust{ExternalIDs: []string{d.ExternalID.Value}}
+	if d.PreviousExternalID.Value != "" {
+		trust.ExternalIDs = append(trust.ExternalIDs, d.PreviousExternalID.Value)
+	}
+	if len(d.Trust) > 0 {
+		trust.UptAccountIDs, _ = listStrings(d.Trust[0].AccountIDs)
+		trust.PrincipalArns, _ = listStrings(d.Trust[0].PrincipalArns)
+		for _, condition := range d.Trust[0].Conditions {
+			values, _ := listStrings(condition.Values)
+			trust.Conditions = append(trust.Conditions, awsinternal.TrustCondition{
+				Test:     condition.Test.Value,
+				Variable: condition.Variable.Value,
+				Values:   values,
+			})
+		}
+	}
+	if len(trust.UptAccountIDs) == 0 && len(trust.PrincipalArns) == 0 && d.UptAccountID.Value != "" {
+		trust.UptAccountIDs = []string{d.UptAccountID.Value}
+	}
+	return trust
+}
+
+// trustKnown reports whether every value trust depends on is known, which
+// is not the case at plan time when they come from resources not created yet.
+func (d exampleResourceData) trustKnown() bool {
+	if d.UptAccountID.Unknown {
+		return false
+	}
+	for _, block := range d.Trust {
+		if _, known := listStrings(block.AccountIDs); !known {
+			return false
+		}
+		if _, known := listStrings(block.PrincipalArns); !known {
+			return false
+		}
+		for _, condition := range block.Conditions {
+			if _, known := listStrings(condition.Values); !known || condition.Test.Unknown || condition.Variable.Unknown {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// planExternalID plans external_id from its configured value and the
+// planned data. Without a configured value, the apply generates it when the
+// role is created and again when rotate_external_id changes, otherwise the
+// generated one, kept by the plan modifier, stays. prior is nil on create.
+func planExternalID(configExternalID types.String, data exampleResourceData, prior *exampleResourceData) types.String {
+	if prior != nil && configExternalID.Null && !data.RotateExternalID.Equal(prior.RotateExternalID) {
+		return types.String{Unknown: true}
+	}
+	return data.ExternalID
+}
+
+// planPreviousExternalID plans the previous external ID the trust policy
+// keeps accepting next to externalID, and until when. A rotation starts when
+// externalID changes with rotation_grace_period set, and ends once confirmed
+// or expired. expired is decided once, when planning, from the stored
+// previous_external_id_expires_at, so that the apply agrees with the plan.
+func planPreviousExternalID(data exampleResourceData, prior exampleResourceData, externalID types.String, expired bool) (types.String, types.String) {
+	none := types.String{Null: true}
+	if data.RotationGracePeriod.Unknown {
+		return types.String{Unknown: true}, types.String{Unknown: true}
+	}
+	if data.RotationGracePeriod.Value == "" || prior.ExternalID.Value == "" {
+		return none, none
+	}
+	if externalID.Unknown || externalID.Value != prior.ExternalID.Value {
+		// The apply sets the end of the rotation.
+		return prior.ExternalID, types.String{Unknown: true}
+	}
+	if prior.PreviousExternalID.Value == "" || data.RotationConfirmed.Value || expired {
+		return none, none
+	}
+	return prior.PreviousExternalID, prior.PreviousExpiresAt
+}
+
+// rotationExpired reports whether the grace period of a rotation ending at
+// expiresAt is over at now. An invalid end is over.
+func rotationExpired(expiresAt types.String, now time.Time) bool {
+	end, err := time.Parse(time.RFC3339, expiresAt.Value)
+	return err != nil || !now.Before(end)
+}
+
+// rotationExpiresAt returns the end of a rotation starting at now, once
+// gracePeriod, which the validator accepted, has passed.
+func rotationExpiresAt(gracePeriod string, now time.Time) types.String {
+	duration, _ := time.ParseDuration(gracePeriod)
+	return types.String{Value: now.Add(duration).UTC().Format(time.RFC3339)}
 }
 
 type roleResource struct {
 	provider provider
 }
 
+// verifyTrust records in data whether Uptycs can assume the role, and warns
+// when it cannot. The role itself is kept, the failure usually comes from
+// values copied from the Uptycs console.
+func (r roleResource) verifyTrust(ctx context.Context, svc *iam.Client, data *exampleResourceData, diags *diag.Diagnostics) {
+	status, err := awsinternal.VerifyIntegrationTrust(ctx, svc, awsinternal.TrustVerification{
+		RoleName:        data.IntegrationName.Value,
+		Trust:           data.trust(),
+		VerifierProfile: data.VerifierProfileName.Value,
+	})
+	data.VerificationStatus = types.String{Value: status}
+	data.VerificationError = types.String{Value: ""}
+	if err != nil {
+		data.VerificationError = types.String{Value: awsErrorMessage(err)}
+		addAwsWarning(diags, fmt.Sprintf("Unable to verify that Uptycs can assume role %s", data.IntegrationName.Value), err)
+	}
+}
+
+// checkPermissions records in data the actions Uptycs requires that the role
+// is not allowed, and warns about them. It runs once the role exists, so it
+// never fails, a permission_check of error fails the next plan instead.
+func (r roleResource) checkPermissions(ctx context.Context, svc *iam.Client, data *exampleResourceData, diags *diag.Diagnostics) {
+	data.MissingPermissions = types.List{ElemType: types.StringType, Null: true}
+	if data.PermissionCheck.Value == permissionCheckOff {
+		return
+	}
+	actions, err := awsinternal.RequiredActions(data.PolicyDocument.Value)
+	if err != nil {
+		diags.AddWarning("Invalid Policy Document", fmt.Sprintf("Unable to list the actions of policy_document. err=%s", err))
+		return
+	}
+	missing, err := awsinternal.SimulateRolePermissions(ctx, svc, data.Role.Value, actions)
+	if err != nil {
+		addAwsWarning(diags, fmt.Sprintf("Unable to simulate the permissions of role %s", data.IntegrationName.Value), err)
+		return
+	}
+	data.MissingPermissions = stringList(missing)
+	if len(missing) > 0 {
+		diags.AddWarning(missingPermissionsSummary, missingPermissionsDetail(*data, missing))
+	}
+}
+
+const missingPermissionsSummary = "Uptycs Role Missing Permissions"
+
+func missingPermissionsDetail(data exampleResourceData, missing []string) string {
+	return fmt.Sprintf("Role %s in account %s is not allowed the following actions Uptycs requires: %s. "+
+		"A permissions boundary, a service control policy or an edit of the role policies may block them.",
+		data.IntegrationName.Value, data.AccountID.Value, strings.Join(missing, ", "))
+}
+
+// planPermissions fails the plan of an existing role whose missing_permissions,
+// refreshed by Read, are not empty when permission_check is error. A role
+// that is created or re-created is checked by the apply, which only warns.
+func planPermissions(ctx context.Context, data exampleResourceData, prior exampleResourceData, diags *diag.Diagnostics) {
+	if data.PermissionCheck.Value != permissionCheckError || prior.MissingPermissions.Null || data.roleChanged(prior) {
+		return
+	}
+	var missing []string
+	diags.Append(prior.MissingPermissions.ElementsAs(ctx, &missing, false)...)
+	if len(missing) == 0 {
+		return
+	}
+	diags.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("missing_permissions"),
+		missingPermissionsSummary, missingPermissionsDetail(data, missing))
+}
+
+// ModifyPlan validates the trust block, plans a new external_id when
+// rotate_external_id changes, keeps the previous one in the trust policy
+// during a rotation, enforces a permission_check of error, and validates the
+// CloudTrail bucket when it, its region or bucket_prefix is set or changed, so
+// that the plan reports a bucket Uptycs would not be able to read the logs of
+// the account from. A bucket in another region than bucket_region or a missing
+// bucket fails the plan, the other problems are warnings. The checks are
+// skipped when the account cannot be reached at plan time, the apply reports
+// why.
+func (r roleResource) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data exampleResourceData
+	diags := req.Plan.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.trustKnown() {
+		trust := data.trust()
+		if len(trust.UptAccountIDs) == 0 && len(trust.PrincipalArns) == 0 {
+			resp.Diagnostics.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("upt_account_id"), "Missing Uptycs Account",
+				"upt_account_id must be set unless the trust block has account_ids or principal_arns.")
+			return
+		}
+		if err := trust.Validate(); err != nil && len(data.Trust) > 0 {
+			resp.Diagnostics.AddAttributeError(tftypes.NewAttributePath().WithAttributeName("trust"), "Invalid Trust", err.Error())
+			return
+		}
+	}
+
+	var prior *exampleResourceData
+	if !req.State.Raw.IsNull() {
+		prior = &exampleResourceData{}
+		diags = req.State.Get(ctx, prior)
+		resp.Diagnostics.Append(diags...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	// A generated external_id is kept in state by its plan modifier, unless
+	// rotate_external_id changed.
+	var configExternalID types.String
+	diags = req.Config.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("external_id"), &configExternalID)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if prior != nil {
+		externalID := planExternalID(configExternalID, data, prior)
+		if !externalID.Equal(data.ExternalID) {
+			diags = resp.Plan.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("external_id"), externalID)
+			resp.Diagnostics.Append(diags...)
+		}
+
+		previous, expiresAt := planPreviousExternalID(data, *prior, externalID, rotationExpired(prior.PreviousExpiresAt, time.Now()))
+		diags = resp.Plan.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("previous_external_id"), previous)
+		resp.Diagnostics.Append(diags...)
+		diags = resp.Plan.SetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("previous_external_id_expires_at"), expiresAt)
+		resp.Diagnostics.Append(diags...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		planPermissions(ctx, data, *prior, &resp.Diagnostics)
+		if expiresAt.Unknown && !previous.Unknown && data.RotationConfirmed.Value {
+			resp.Diagnostics.AddWarning("External ID Rotation Already Confirmed",
+				fmt.Sprintf("rotation_confirmed of role %s is still true from the previous rotation, the apply following this one "+
+					"will remove the previous external ID before Uptycs uses the new one. Set rotation_confirmed to false.", data.IntegrationName.Value))
+		}
+	}
+
+	if data.BucketName.Unknown || data.BucketName.Value == "" || data.BucketRegion.Unknown || data.BucketPrefix.Unknown ||
+		data.ProfileName.Unknown || data.AccountID.Unknown || data.OrgAccessRoleName.Unknown || data.UseCallerCredentials.Unknown {
+		return
+	}
+	if prior != nil && prior.BucketName.Value == data.BucketName.Value && prior.BucketRegion.Value == data.BucketRegion.Value &&
+		prior.BucketPrefix.Value == data.BucketPrefix.Value {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
+	s3Client, err := awsinternal.GetAwsS3Client(ctx, data.ProfileName.Value, data.BucketRegion.Value, data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if err != nil {
+		tflog.Debug(ctx, "skipping CloudTrail bucket validation", map[string]interface{}{
+			"account_id": data.AccountID.Value,
+			"error":      err.Error(),
+		})
+		return
+	}
+	problems, err := awsinternal.ValidateCloudTrailBucket(ctx, s3Client, data.BucketName.Value, data.BucketRegion.Value, data.BucketPrefix.Value, data.AccountID.Value)
+	if err != nil {
+		if awsinternal.IsKind(err, awsinternal.ErrBucketNotFound) {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to validate CloudTrail bucket %s", data.BucketName.Value), err)
+			return
+		}
+		addAwsWarning(&resp.Diagnostics, fmt.Sprintf("Unable to validate CloudTrail bucket %s", data.BucketName.Value), err)
+		return
+	}
+	for _, problem := range problems {
+		action := fmt.Sprintf("CloudTrail bucket %s of account %s", data.BucketName.Value, data.AccountID.Value)
+		if problem.Kind == awsinternal.ErrBucketWrongRegion {
+			addAwsError(&resp.Diagnostics, action, problem)
+			continue
+		}
+		addAwsWarning(&resp.Diagnostics, action, problem)
+	}
+}
+
 func (r roleResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
 	var data exampleResourceData
 
-	diags := req.Config.Get(ctx, &data)
+	diags := req.Plan.Get(ctx, &data)
 	resp.Diagnostics.Append(diags...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "create", defaultCreateTimeout)
+	defer cancel()
+
+	if data.ExternalID.Unknown {
+		externalID, err := awsinternal.GenerateExternalID()
+		if err != nil {
+			resp.Diagnostics.AddError("External ID Error", err.Error())
+			return
+		}
+		data.ExternalID = types.String{Value: externalID}
+	}
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// example, err := d.provider.client.CreateExample(...)
@@ -127,28 +602,39 @@ func (r roleResource) Create(ctx context.Context, req tfsdk.CreateResourceReques
 
 	// For the purposes of this example code, hardcoding a response value to
 	// save into the Terraform state.
-	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value)
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
 	if errSvc != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get client for %s with profile %s. err=%s", data.AccountID.Value, data.ProfileName.Value, errSvc.Error()))
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
 		return
 	}
 	role, errCreate := awsinternal.CreateUptycsCspmResources(ctx,
 		svc,
 		data.IntegrationName.Value,
-		data.UptAccountID.Value,
-		data.ExternalID.Value,
+		data.trust(),
 		data.BucketName.Value,
 		data.BucketRegion.Value,
+		data.BucketPrefix.Value,
 		data.ProfileName.Value,
 		data.AccountID.Value,
 		data.PolicyDocument.Value,
 		data.OrgAccessRoleName.Value,
-		false)
+		data.UseCallerCredentials.Value)
 	if errCreate != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create uptycscspm role. err=%s", errCreate))
+		addAwsError(&resp.Diagnostics, "Unable to create uptycscspm role", errCreate)
 		return
 	}
 	data.Role = types.String{Value: role}
+	// A role replaced during a rotation keeps the planned previous external
+	// ID, which Uptycs may still use.
+	if data.PreviousExternalID.Unknown {
+		data.PreviousExternalID = types.String{Null: true}
+	}
+	if data.PreviousExpiresAt.Unknown {
+		data.PreviousExpiresAt = types.String{Null: true}
+	}
+	data.Drift = types.String{Value: ""}
+	r.verifyTrust(ctx, svc, &data, &resp.Diagnostics)
+	r.checkPermissions(ctx, svc, &data, &resp.Diagnostics)
 
 	// write logs using the tflog package
 	// see https://pkg.go.dev/github.com/hashicorp/terraform-plugin-log/tflog
@@ -169,6 +655,9 @@ func (r roleResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, r
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "read", defaultReadTimeout)
+	defer cancel()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// example, err := d.provider.client.ReadExample(...)
@@ -177,49 +666,92 @@ func (r roleResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, r
 	//     return
 	// }
 
-	orgSvc, orgErrSvc := awsinternal.GetOrgClient(ctx, data.ProfileName.Value)
-	if orgErrSvc != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get org client with profile %s. err=%s", data.ProfileName.Value, orgErrSvc.Error()))
-		return
+	if !data.Standalone.Value {
+		account, err := r.provider.orgInventory.Account(ctx, data.ProfileName.Value, data.AccountID.Value)
+		if err != nil {
+			addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get account list from organization with profile %s", data.ProfileName.Value), err)
+			return
+		}
+
+		if account == nil || account.Status != awsinternal.AccountStatusActive {
+			resp.State.RemoveResource(ctx)
+			return
+		}
 	}
 
-	accountExists, err := awsinternal.IsAccountExistsInOrg(ctx, orgSvc, data.AccountID.Value)
-	if err != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get account list from organization. err=%s", orgErrSvc.Error()))
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+	if errSvc != nil {
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
 		return
 	}
-
-	if !accountExists {
+	status, errRole := awsinternal.GetIntegrationRoleStatus(ctx, svc, data.IntegrationName.Value)
+	if awsinternal.IsKind(errRole, awsinternal.ErrNoSuchEntity) {
+		// The role was deleted outside of Terraform, plan to re-create it.
+		tflog.Warn(ctx, "uptycscspm role not found, removing from state", map[string]interface{}{
+			"account_id":       data.AccountID.Value,
+			"integration_name": data.IntegrationName.Value,
+		})
 		resp.State.RemoveResource(ctx)
 		return
 	}
-
-	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value)
-	if errSvc != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get client for %s with profile %s. err=%s", data.AccountID.Value, data.ProfileName.Value, errSvc.Error()))
-		return
-	}
-	role, errRole := awsinternal.GetIntegrationRoleName(ctx, svc, data.IntegrationName.Value)
 	if errRole != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get uptycscspm role. err=%s", errRole))
+		addAwsError(&resp.Diagnostics, "Unable to get uptycscspm role", errRole)
 		return
 	}
-	data.Role = types.String{Value: role}
+	data.Role = types.String{Value: status.Arn}
+
+	bucketPolicyArn := ""
+	if data.BucketName.Value != "" {
+		bucketPolicyArn = awsinternal.GetCloudtrailBucketPolicyArn(data.AccountID.Value, data.IntegrationName.Value)
+	}
+	// The configured attributes are kept as they are, the drift plans the
+	// replacement of the role, which restores the missing policies.
+	data.Drift = types.String{Value: ""}
+	if missing := status.MissingPolicies(bucketPolicyArn); len(missing) > 0 {
+		data.Drift = types.String{Value: "missing policies " + strings.Join(missing, ", ")}
+		resp.Diagnostics.AddWarning("Uptycs Role Drift Detected",
+			fmt.Sprintf("Role %s in account %s is missing the following policies: %s. "+
+				"The next apply re-creates the role to restore them.", data.IntegrationName.Value, data.AccountID.Value, strings.Join(missing, ", ")))
+	}
+	r.verifyTrust(ctx, svc, &data, &resp.Diagnostics)
+	r.checkPermissions(ctx, svc, &data, &resp.Diagnostics)
 
 	diags = resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
 }
 
 func (r roleResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
-	var data exampleResourceData
+	var data, prior exampleResourceData
 
 	diags := req.Plan.Get(ctx, &data)
 	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &prior)
+	resp.Diagnostics.Append(diags...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "update", defaultUpdateTimeout)
+	defer cancel()
+
+	if data.ExternalID.Unknown {
+		externalID, err := awsinternal.GenerateExternalID()
+		if err != nil {
+			resp.Diagnostics.AddError("External ID Error", err.Error())
+			return
+		}
+		data.ExternalID = types.String{Value: externalID}
+	}
+	if data.PreviousExternalID.Unknown {
+		// rotation_grace_period was unknown when planned. An expired
+		// rotation is only ended by the next plan, never by the apply.
+		data.PreviousExternalID, data.PreviousExpiresAt = planPreviousExternalID(data, prior, data.ExternalID, false)
+	}
+	if data.PreviousExpiresAt.Unknown {
+		data.PreviousExpiresAt = rotationExpiresAt(data.RotationGracePeriod.Value, time.Now())
+	}
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// example, err := d.provider.client.UpdateExample(...)
@@ -227,33 +759,58 @@ func (r roleResource) Update(ctx context.Context, req tfsdk.UpdateResourceReques
 	//     resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update example, got error: %s", err))
 	//     return
 	// }
-	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value)
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
 	if errSvc != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get client for %s with profile %s. err=%s", data.AccountID.Value, data.ProfileName.Value, errSvc.Error()))
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
 		return
 	}
-	errDel := awsinternal.DeleteUptycsCspmResources(ctx, svc, data.IntegrationName.Value)
-	if errDel != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to update uptycscspm role. err=%s", errDel))
-		return
+	if !data.roleChanged(prior) {
+		// Only the trust changed, update it in place so that Uptycs keeps
+		// access to the role.
+		errTrust := awsinternal.UpdateIntegrationTrust(ctx, svc, data.IntegrationName.Value, data.trust())
+		if errTrust != nil {
+			addAwsError(&resp.Diagnostics, "Unable to update trust policy of uptycscspm role", errTrust)
+			return
+		}
+		data.Role = prior.Role
+	} else {
+		// Check what can be checked before the role is deleted, so that a
+		// wrong bucket does not leave the account without a role. A later
+		// failure removes the partial role, which the next plan re-creates.
+		if data.BucketName.Value != "" {
+			errBucket := awsinternal.CheckIntegrationBucket(ctx, data.ProfileName.Value, data.BucketName.Value, data.BucketRegion.Value,
+				data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
+			if errBucket != nil {
+				addAwsError(&resp.Diagnostics, "Unable to update uptycscspm role", errBucket)
+				return
+			}
+		}
+		errDel := awsinternal.DeleteUptycsCspmResources(ctx, svc, data.IntegrationName.Value)
+		if errDel != nil {
+			addAwsError(&resp.Diagnostics, "Unable to update uptycscspm role", errDel)
+			return
+		}
+		role, errCreate := awsinternal.CreateUptycsCspmResources(ctx,
+			svc,
+			data.IntegrationName.Value,
+			data.trust(),
+			data.BucketName.Value,
+			data.BucketRegion.Value,
+			data.BucketPrefix.Value,
+			data.ProfileName.Value,
+			data.AccountID.Value,
+			data.PolicyDocument.Value,
+			data.OrgAccessRoleName.Value,
+			data.UseCallerCredentials.Value)
+		if errCreate != nil {
+			addAwsError(&resp.Diagnostics, "Unable to re-create uptycscspm role", errCreate)
+			return
+		}
+		data.Role = types.String{Value: role}
 	}
-	role, errCreate := awsinternal.CreateUptycsCspmResources(ctx,
-		svc,
-		data.IntegrationName.Value,
-		data.UptAccountID.Value,
-		data.ExternalID.Value,
-		data.BucketName.Value,
-		data.BucketRegion.Value,
-		data.ProfileName.Value,
-		data.AccountID.Value,
-		data.PolicyDocument.Value,
-		data.OrgAccessRoleName.Value,
-		true)
-	if errCreate != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to re-create uptycscspm role. err=%s", errCreate))
-		return
-	}
-	data.Role = types.String{Value: role}
+	data.Drift = types.String{Value: ""}
+	r.verifyTrust(ctx, svc, &data, &resp.Diagnostics)
+	r.checkPermissions(ctx, svc, &data, &resp.Diagnostics)
 	diags = resp.State.Set(ctx, &data)
 	resp.Diagnostics.Append(diags...)
 }
@@ -268,6 +825,9 @@ func (r roleResource) Delete(ctx context.Context, req tfsdk.DeleteResourceReques
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, data.Timeouts, "delete", defaultDeleteTimeout)
+	defer cancel()
+
 	// If applicable, this is a great opportunity to initialize any necessary
 	// provider client data and make a call using it.
 	// example, err := d.provider.client.DeleteExample(...)
@@ -275,14 +835,24 @@ func (r roleResource) Delete(ctx context.Context, req tfsdk.DeleteResourceReques
 	//     resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to delete example, got error: %s", err))
 	//     return
 	// }
-	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value)
+	svc, errSvc := awsinternal.GetAwsIamClient(ctx, data.ProfileName.Value, "aws-global", data.AccountID.Value, data.OrgAccessRoleName.Value, data.UseCallerCredentials.Value)
 	if errSvc != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to get client for %s with profile %s. err=%s", data.AccountID.Value, data.ProfileName.Value, errSvc.Error()))
+		addAwsError(&resp.Diagnostics, fmt.Sprintf("Unable to get client for %s with profile %s", data.AccountID.Value, data.ProfileName.Value), errSvc)
+		return
+	}
+	// Only a missing role means it was already deleted outside of
+	// Terraform, a missing policy fails the deletion like any other error.
+	_, errGet := awsinternal.GetIntegrationRoleName(ctx, svc, data.IntegrationName.Value)
+	if awsinternal.IsKind(errGet, awsinternal.ErrNoSuchEntity) {
+		return
+	}
+	if errGet != nil {
+		addAwsError(&resp.Diagnostics, "Unable to get uptycscspm role", errGet)
 		return
 	}
 	errDel := awsinternal.DeleteUptycsCspmResources(ctx, svc, data.IntegrationName.Value)
 	if errDel != nil {
-		resp.Diagnostics.AddError("Client Error", fmt.Sprintf("Unable to create uptycscspm role. err=%s", errDel))
+		addAwsError(&resp.Diagnostics, "Unable to delete uptycscspm role", errDel)
 		return
 	}
 }
@@ -1,10 +1,14 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -61,6 +65,65 @@ func TestAccRoleResource(t *testing.T) {
 	})
 }
 
+func TestAccRoleResourceGeneratedExternalID(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "uptycscspm_role" "test" {
+  profile_name = "noprofile"
+  account_id = "123456789012"
+  upt_account_id = "012345678912"
+  integration_name = "uptcloud"
+  bucket_name = "uptycs-test-bucket"
+  bucket_region = "us-east-1"
+  policy_document = ""
+  org_access_role_name = "OrganizationAccountAccessRole"
+  rotate_external_id = "1"
+}
+`,
+				// Expect to fail as we cannot contact AWS with fake accounts
+				ExpectError: errRegex,
+			},
+		},
+	})
+}
+
+func TestAccRoleResourceInvalidTrust(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "uptycscspm_role" "test" {
+  profile_name = "noprofile"
+  account_id = "123456789012"
+  upt_account_id = "012345678912"
+  integration_name = "uptcloud"
+  bucket_name = "uptycs-test-bucket"
+  bucket_region = "us-east-1"
+  policy_document = ""
+
+  trust {
+    account_ids = ["012345678912"]
+
+    condition {
+      test     = "StringEquals"
+      variable = "sts:ExternalId"
+      values   = ["other"]
+    }
+  }
+}
+`,
+				ExpectError: regexp.MustCompile("trust condition cannot test sts:ExternalId"),
+			},
+		},
+	})
+}
+
 func testAccRoleResourceConfig(profile string, account string, uptAccount string, integration string, externalID string, bucketName string, bucketRegion string, policyDocument string, orgAccessRoleName string) string {
 	return fmt.Sprintf(`
 resource "uptycscspm_role" "test" {
@@ -77,3 +140,140 @@ resource "uptycscspm_role" "test" {
 }
 `, profile, account, uptAccount, integration, externalID, bucketName, bucketRegion, policyDocument, orgAccessRoleName)
 }
+
+func TestPlanExternalID(t *testing.T) {
+	known := func(value string) types.String { return types.String{Value: value} }
+	unknown := types.String{Unknown: true}
+	none := types.String{Null: true}
+	prior := &exampleResourceData{ExternalID: known("generated"), RotateExternalID: known("1")}
+
+	cases := []struct {
+		name     string
+		config   types.String
+		data     exampleResourceData
+		prior    *exampleResourceData
+		expected types.String
+	}{
+		{"generated on create", none, exampleResourceData{ExternalID: unknown}, nil, unknown},
+		{"configured on create", known("configured"), exampleResourceData{ExternalID: known("configured")}, nil, known("configured")},
+		{"stable", none, exampleResourceData{ExternalID: known("generated"), RotateExternalID: known("1")}, prior, known("generated")},
+		{"rotated", none, exampleResourceData{ExternalID: known("generated"), RotateExternalID: known("2")}, prior, unknown},
+		{"without rotate_external_id", none, exampleResourceData{ExternalID: known("generated"), RotateExternalID: none}, &exampleResourceData{ExternalID: known("generated"), RotateExternalID: none}, known("generated")},
+		{"rotation ignored when configured", known("configured"), exampleResourceData{ExternalID: known("configured"), RotateExternalID: known("2")}, prior, known("configured")},
+		{"unknown rotation", none, exampleResourceData{ExternalID: known("generated"), RotateExternalID: unknown}, prior, unknown},
+	}
+	for _, c := range cases {
+		if externalID := planExternalID(c.config, c.data, c.prior); !externalID.Equal(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, externalID)
+		}
+	}
+}
+
+func TestPlanPreviousExternalID(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	known := func(value string) types.String { return types.String{Value: value} }
+	unknown := types.String{Unknown: true}
+	none := types.String{Null: true}
+	rotating := exampleResourceData{
+		ExternalID:         known("new"),
+		PreviousExternalID: known("old"),
+		PreviousExpiresAt:  known("2024-05-02T12:00:00Z"),
+	}
+
+	cases := []struct {
+		name        string
+		gracePeriod types.String
+		confirmed   bool
+		prior       exampleResourceData
+		externalID  types.String
+		previous    types.String
+		expiresAt   types.String
+	}{
+		{"unknown grace period", unknown, false, exampleResourceData{ExternalID: known("old")}, known("new"), unknown, unknown},
+		{"no grace period", known(""), false, exampleResourceData{ExternalID: known("old")}, known("new"), none, none},
+		{"no prior external ID", known("72h"), false, exampleResourceData{}, known("new"), none, none},
+		{"start", known("72h"), false, exampleResourceData{ExternalID: known("old")}, known("new"), known("old"), unknown},
+		{"start with a generated external ID", known("72h"), false, exampleResourceData{ExternalID: known("old")}, unknown, known("old"), unknown},
+		{"no rotation", known("72h"), false, exampleResourceData{ExternalID: known("old")}, known("old"), none, none},
+		{"during the grace period", known("72h"), false, rotating, known("new"), known("old"), known("2024-05-02T12:00:00Z")},
+		{"confirm", known("72h"), true, rotating, known("new"), none, none},
+		{"expire", known("72h"), false, exampleResourceData{
+			ExternalID:         known("new"),
+			PreviousExternalID: known("old"),
+			PreviousExpiresAt:  known("2024-05-01T12:00:00Z"),
+		}, known("new"), none, none},
+		{"invalid expiry", known("72h"), false, exampleResourceData{
+			ExternalID:         known("new"),
+			PreviousExternalID: known("old"),
+			PreviousExpiresAt:  known("tomorrow"),
+		}, known("new"), none, none},
+	}
+	for _, c := range cases {
+		data := exampleResourceData{
+			RotationGracePeriod: c.gracePeriod,
+			RotationConfirmed:   types.Bool{Value: c.confirmed},
+		}
+		previous, expiresAt := planPreviousExternalID(data, c.prior, c.externalID, rotationExpired(c.prior.PreviousExpiresAt, now))
+		if !previous.Equal(c.previous) || !expiresAt.Equal(c.expiresAt) {
+			t.Errorf("%s: expected %v until %v, got %v until %v", c.name, c.previous, c.expiresAt, previous, expiresAt)
+		}
+	}
+}
+
+func TestPlanPreviousExternalIDAtApply(t *testing.T) {
+	// The grace period was unknown when planned and ended before the apply,
+	// which keeps the previous external ID for the next plan to remove.
+	data := exampleResourceData{RotationGracePeriod: types.String{Value: "72h"}}
+	prior := exampleResourceData{
+		ExternalID:         types.String{Value: "new"},
+		PreviousExternalID: types.String{Value: "old"},
+		PreviousExpiresAt:  types.String{Value: "2024-05-01T12:00:00Z"},
+	}
+	previous, expiresAt := planPreviousExternalID(data, prior, prior.ExternalID, false)
+	if previous.Value != "old" || expiresAt.Value != "2024-05-01T12:00:00Z" {
+		t.Errorf("expected the previous external ID to be kept, got %v until %v", previous, expiresAt)
+	}
+	if !rotationExpired(prior.PreviousExpiresAt, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Error("expected the rotation to be over at its end")
+	}
+}
+
+func TestRotationExpiresAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	if expiresAt := rotationExpiresAt("72h", now); expiresAt.Value != "2024-05-04T10:00:00Z" {
+		t.Errorf("expected the end of the grace period in UTC, got %v", expiresAt)
+	}
+}
+
+func TestPlanPermissions(t *testing.T) {
+	known := func(value string) types.String { return types.String{Value: value} }
+	missing := stringList([]string{"iam:ListRoles"})
+	none := types.List{ElemType: types.StringType, Null: true}
+	role := exampleResourceData{AccountID: known("123456789012"), IntegrationName: known("uptcloud"), PermissionCheck: known(permissionCheckError)}
+	moved := role
+	moved.AccountID = known("234567890123")
+	warning := role
+	warning.PermissionCheck = known(permissionCheckWarning)
+
+	cases := []struct {
+		name        string
+		data        exampleResourceData
+		missing     types.List
+		expectError bool
+	}{
+		{"missing permissions", role, missing, true},
+		{"no missing permissions", role, stringList(nil), false},
+		{"not simulated", role, none, false},
+		{"only warned", warning, missing, false},
+		{"role re-created", moved, missing, false},
+	}
+	for _, c := range cases {
+		prior := role
+		prior.MissingPermissions = c.missing
+		var diags diag.Diagnostics
+		planPermissions(context.Background(), c.data, prior, &diags)
+		if diags.HasError() != c.expectError {
+			t.Errorf("%s: expected an error %t, got %v", c.name, c.expectError, diags)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ tfsdk.DataSourceType = tenantTrustDataSourceType{}
+var _ tfsdk.DataSource = tenantTrustDataSource{}
+
+type tenantTrustDataSourceType struct{}
+
+func (t tenantTrustDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	_ = ctx
+	return tfsdk.Schema{
+		MarkdownDescription: "Reads from the Uptycs tenant the principal account and the external ID the integration role of an AWS account " +
+			"must trust. Requires the Uptycs API settings of the provider",
+
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				MarkdownDescription: "AWS account ID",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"account_id": {
+				MarkdownDescription: "AWS account ID of the integration role",
+				Required:            true,
+				Type:                types.StringType,
+			},
+			"upt_account_id": {
+				MarkdownDescription: "Uptycs AWS account ID, for the `upt_account_id` of `uptycscspm_role`",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"upt_principal_arn": {
+				MarkdownDescription: "ARN of the role the tenant assumes integration roles from, empty when the tenant does not report one",
+				Computed:            true,
+				Type:                types.StringType,
+			},
+			"external_id": {
+				MarkdownDescription: "External ID issued by the tenant for the account, for the `external_id` of `uptycscspm_role`",
+				Computed:            true,
+				Sensitive:           true,
+				Type:                types.StringType,
+			},
+		},
+	}, nil
+}
+
+func (t tenantTrustDataSourceType) NewDataSource(ctx context.Context, in tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	_ = ctx
+	provider, diags := convertProviderType(in)
+
+	return tenantTrustDataSource{
+		provider: provider,
+	}, diags
+}
+
+type tenantTrustDataSourceData struct {
+	ID              types.String `tfsdk:"id"`
+	AccountID       types.String `tfsdk:"account_id"`
+	UptAccountID    types.String `tfsdk:"upt_account_id"`
+	UptPrincipalArn types.String `tfsdk:"upt_principal_arn"`
+	ExternalID      types.String `tfsdk:"external_id"`
+}
+
+type tenantTrustDataSource struct {
+	provider provider
+}
+
+func (d tenantTrustDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var data tenantTrustDataSourceData
+
+	diags := req.Config.Get(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := d.provider.uptycsClient(&resp.Diagnostics)
+	if client == nil {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, nil, "read", defaultReadTimeout)
+	defer cancel()
+
+	trust, err := client.GetAwsTrust(ctx, data.AccountID.Value)
+	if err != nil {
+		addUptycsError(&resp.Diagnostics, fmt.Sprintf("Unable to get Uptycs trust settings for account %s", data.AccountID.Value), err)
+		return
+	}
+	if trust.AccountID == "" || trust.ExternalID == "" {
+		resp.Diagnostics.AddError("Uptycs API Error",
+			fmt.Sprintf("The Uptycs tenant returned incomplete trust settings for account %s.", data.AccountID.Value))
+		return
+	}
+
+	data.ID = types.String{Value: data.AccountID.Value}
+	data.UptAccountID = types.String{Value: trust.AccountID}
+	data.UptPrincipalArn = types.String{Value: trust.PrincipalArn}
+	data.ExternalID = types.String{Value: trust.ExternalID}
+
+	diags = resp.State.Set(ctx, &data)
+	resp.Diagnostics.Append(diags...)
+}
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccTenantTrustDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Expect to fail as the provider has no Uptycs API key
+			{
+				Config:      testAccTenantTrustDataSourceConfig("123456789012"),
+				ExpectError: regexp.MustCompile("Uptycs API Not Configured"),
+			},
+		},
+	})
+}
+
+func testAccTenantTrustDataSourceConfig(account string) string {
+	return fmt.Sprintf(`
+data "uptycscspm_tenant_trust" "test" {
+  account_id = %[1]q
+}
+`, account)
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultReadTimeout   = 5 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 20 * time.Minute
+)
+
+// timeoutsData is the value of the optional timeouts block. Each value is a
+// Go duration string such as "30s" or "10m".
+type timeoutsData struct {
+	Create types.String `tfsdk:"create"`
+	Read   types.String `tfsdk:"read"`
+	Update types.String `tfsdk:"update"`
+	Delete types.String `tfsdk:"delete"`
+}
+
+// timeoutsBlock returns the schema of the timeouts block shared by resources
+// that talk to AWS.
+func timeoutsBlock() tfsdk.Block {
+	durationAttribute := func(op string, def string) tfsdk.Attribute {
+		return tfsdk.Attribute{
+			MarkdownDescription: fmt.Sprintf("Timeout for the %s operation, as a duration string such as `30s` or `10m`. Defaults to `%s`.", op, def),
+			Optional:            true,
+			Type:                types.StringType,
+			Validators:          []tfsdk.AttributeValidator{durationValidator{}},
+		}
+	}
+	return tfsdk.Block{
+		MarkdownDescription: "Operation timeouts",
+		NestingMode:         tfsdk.BlockNestingModeList,
+		MaxItems:            1,
+		Attributes: map[string]tfsdk.Attribute{
+			"create": durationAttribute("create", "20m"),
+			"read":   durationAttribute("read", "5m"),
+			"update": durationAttribute("update", "20m"),
+			"delete": durationAttribute("delete", "20m"),
+		},
+	}
+}
+
+// withTimeout derives a context bounded by the timeout configured for op, or
+// by def when the block or value is not set.
+func withTimeout(ctx context.Context, timeouts []timeoutsData, op string, def time.Duration) (context.Context, context.CancelFunc) {
+	timeout := def
+	if len(timeouts) > 0 {
+		var value types.String
+		switch op {
+		case "create":
+			value = timeouts[0].Create
+		case "read":
+			value = timeouts[0].Read
+		case "update":
+			value = timeouts[0].Update
+		case "delete":
+			value = timeouts[0].Delete
+		}
+		if !value.Null && !value.Unknown && value.Value != "" {
+			// the value has already been checked by durationValidator
+			if d, err := time.ParseDuration(value.Value); err == nil {
+				timeout = d
+			}
+		}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ tfsdk.AttributeValidator = durationValidator{}
+var _ tfsdk.AttributeValidator = regexValidator{}
+var _ tfsdk.AttributeValidator = oneOfValidator{}
+var _ tfsdk.AttributeValidator = int64BetweenValidator{}
+var _ tfsdk.AttributeValidator = failureThresholdValidator{}
+
+// durationValidator checks that a string attribute is a positive Go duration.
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration such as 30s or 10m"
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return "value must be a positive duration such as `30s` or `10m`"
+}
+
+func (v durationValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+	if d, err := time.ParseDuration(value.Value); err != nil || d <= 0 {
+		resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid Duration", fmt.Sprintf("%q is not a valid duration: %s.", value.Value, v.Description(ctx)))
+	}
+}
+
+// regexValidator checks that a string attribute is a valid regular expression.
+type regexValidator struct{}
+
+func (v regexValidator) Description(ctx context.Context) string {
+	return "value must be a valid regular expression"
+}
+
+func (v regexValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v regexValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+	if _, err := regexp.Compile(value.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid Regular Expression", fmt.Sprintf("%q is not a valid regular expression: %s.", value.Value, err))
+	}
+}
+
+// oneOfValidator checks that a string attribute is one of a fixed set of
+// values.
+type oneOfValidator struct {
+	values []string
+}
+
+func (v oneOfValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be one of %s", strings.Join(v.values, ", "))
+}
+
+func (v oneOfValidator) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("value must be one of `%s`", strings.Join(v.values, "`, `"))
+}
+
+func (v oneOfValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+	for _, allowed := range v.values {
+		if value.Value == allowed {
+			return
+		}
+	}
+	resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid Value", fmt.Sprintf("%q is not valid: %s.", value.Value, v.Description(ctx)))
+}
+
+// int64BetweenValidator checks that a number attribute is between min and
+// max, inclusive.
+type int64BetweenValidator struct {
+	min int64
+	max int64
+}
+
+func (v int64BetweenValidator) Description(ctx context.Context) string {
+	return fmt.Sprintf("value must be between %d and %d", v.min, v.max)
+}
+
+func (v int64BetweenValidator) MarkdownDescription(ctx context.Context) string {
+	return fmt.Sprintf("value must be between `%d` and `%d`", v.min, v.max)
+}
+
+func (v int64BetweenValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.Int64
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+	if value.Value < v.min || value.Value > v.max {
+		resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid Value", fmt.Sprintf("%d is not valid: %s.", value.Value, v.Description(ctx)))
+	}
+}
+
+// failureThresholdValidator checks that a string attribute is a number of
+// failures such as 5, or a percentage such as 10%.
+type failureThresholdValidator struct{}
+
+func (v failureThresholdValidator) Description(ctx context.Context) string {
+	return "value must be a number such as 5 or a percentage such as 10%"
+}
+
+func (v failureThresholdValidator) MarkdownDescription(ctx context.Context) string {
+	return "value must be a number such as `5` or a percentage such as `10%`"
+}
+
+func (v failureThresholdValidator) Validate(ctx context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	var value types.String
+	resp.Diagnostics.Append(tfsdk.ValueAs(ctx, req.AttributeConfig, &value)...)
+	if resp.Diagnostics.HasError() || value.Null || value.Unknown {
+		return
+	}
+	if _, _, err := parseFailureThreshold(value.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid Failure Threshold", fmt.Sprintf("%q is not valid: %s.", value.Value, v.Description(ctx)))
+	}
+}
+
+// parseFailureThreshold parses a failure threshold such as 5 or 10%.
+func parseFailureThreshold(value string) (int64, bool, error) {
+	number := strings.TrimSuffix(value, "%")
+	percent := number != value
+	threshold, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	if threshold < 0 || (percent && threshold > 100) {
+		return 0, false, fmt.Errorf("%s is out of range", value)
+	}
+	return threshold, percent, nil
+}
@@ -0,0 +1,145 @@
+package uptycs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+const (
+	cloudAccountsPath = "/cloudAccounts"
+
+	// ConnectorTypeAws is the connector type of the AWS accounts.
+	ConnectorTypeAws = "aws"
+
+	// LogSourceCloudTrail is the type of the log sources delivering
+	// CloudTrail logs from a bucket.
+	LogSourceCloudTrail = "cloudtrail"
+)
+
+// AwsAccount is the registration of an AWS account with the tenant.
+type AwsAccount struct {
+	// ID is assigned by the tenant on creation.
+	ID            string          `json:"id,omitempty"`
+	AccountID     string          `json:"tenantId"`
+	Name          string          `json:"tenantName"`
+	ConnectorType string          `json:"connectorType"`
+	AccessConfig  AwsAccessConfig `json:"accessConfig"`
+	LogSources    []AwsLogSource  `json:"logSources"`
+
+	// Status is reported by the tenant, such as whether the role could be
+	// assumed.
+	Status string `json:"status,omitempty"`
+}
+
+// AwsAccessConfig is how the tenant gets into the account.
+type AwsAccessConfig struct {
+	RoleArn    string `json:"roleArn"`
+	ExternalID string `json:"externalId"`
+}
+
+// AwsLogSource is a bucket the tenant reads logs from, along with the queue
+// notifying it of new logs when there is one.
+type AwsLogSource struct {
+	Type         string `json:"type"`
+	BucketName   string `json:"bucketName"`
+	BucketRegion string `json:"bucketRegion"`
+	BucketPrefix string `json:"bucketPrefix,omitempty"`
+	QueueURL     string `json:"queueUrl,omitempty"`
+}
+
+func awsAccountPath(id string) string {
+	return cloudAccountsPath + "/" + url.PathEscape(id)
+}
+
+// CreateAwsAccount registers account with the tenant and returns the
+// registration, with its ID.
+func (c *Client) CreateAwsAccount(ctx context.Context, account AwsAccount) (*AwsAccount, error) {
+	account.ID = ""
+	account.ConnectorType = ConnectorTypeAws
+	var created AwsAccount
+	if err := c.do(ctx, http.MethodPost, cloudAccountsPath, nil, account, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetAwsAccount returns the registration with the ID. An ErrNotFound error
+// is returned when there is none.
+func (c *Client) GetAwsAccount(ctx context.Context, id string) (*AwsAccount, error) {
+	var account AwsAccount
+	if err := c.do(ctx, http.MethodGet, awsAccountPath(id), nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// FindAwsAccount returns the registration of the AWS account, or nil when
+// the account is not registered.
+func (c *Client) FindAwsAccount(ctx context.Context, accountID string) (*AwsAccount, error) {
+	var found *AwsAccount
+	query := url.Values{"connectorType": {ConnectorTypeAws}}
+	err := c.list(ctx, cloudAccountsPath, query, func(items json.RawMessage) (int, error) {
+		var page []AwsAccount
+		if err := json.Unmarshal(items, &page); err != nil {
+			return 0, err
+		}
+		for i := range page {
+			if page[i].AccountID == accountID && found == nil {
+				found = &page[i]
+			}
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// UpdateAwsAccount replaces the registration with the ID of account.
+func (c *Client) UpdateAwsAccount(ctx context.Context, account AwsAccount) (*AwsAccount, error) {
+	account.ConnectorType = ConnectorTypeAws
+	var updated AwsAccount
+	if err := c.do(ctx, http.MethodPut, awsAccountPath(account.ID), nil, account, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteAwsAccount removes the registration with the ID. A registration
+// that no longer exists is skipped.
+func (c *Client) DeleteAwsAccount(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, awsAccountPath(id), nil, nil, nil)
+	if IsKind(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// AwsTrust is what the integration role of an AWS account has to trust for
+// the tenant to assume it.
+type AwsTrust struct {
+	// AccountID is the AWS account of the tenant principals.
+	AccountID string `json:"accountId"`
+
+	// PrincipalArn is the role the tenant assumes integration roles from,
+	// when the tenant reports one.
+	PrincipalArn string `json:"principalArn,omitempty"`
+
+	// ExternalID is issued by the tenant for the integrated AWS account and
+	// stays the same across calls.
+	ExternalID string `json:"externalId"`
+}
+
+// GetAwsTrust returns the trust settings the tenant expects from the
+// integration role of the AWS account.
+func (c *Client) GetAwsTrust(ctx context.Context, accountID string) (*AwsTrust, error) {
+	var trust AwsTrust
+	query := url.Values{"tenantId": {accountID}}
+	if err := c.do(ctx, http.MethodGet, cloudAccountsPath+"/awsTrust", query, nil, &trust); err != nil {
+		return nil, err
+	}
+	return &trust, nil
+}
@@ -0,0 +1,143 @@
+package uptycs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeTenant is an httptest stand-in for the cloud accounts API of a tenant.
+type fakeTenant struct {
+	mu       sync.Mutex
+	accounts map[string]AwsAccount
+	nextID   int
+}
+
+func (f *fakeTenant) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/public/api/customers/customer"+cloudAccountsPath)
+	id := strings.TrimPrefix(path, "/")
+	switch {
+	case r.Method == http.MethodGet && id == "awsTrust":
+		json.NewEncoder(w).Encode(AwsTrust{AccountID: "012345678912", ExternalID: "ext-" + r.URL.Query().Get("tenantId")})
+	case r.Method == http.MethodGet && id == "":
+		items := []AwsAccount{}
+		for _, account := range f.accounts {
+			items = append(items, account)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case r.Method == http.MethodPost && id == "":
+		var account AwsAccount
+		json.NewDecoder(r.Body).Decode(&account)
+		for _, existing := range f.accounts {
+			if existing.AccountID == account.AccountID {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+		f.nextID++
+		account.ID = strings.Repeat("a", f.nextID)
+		account.Status = "active"
+		f.accounts[account.ID] = account
+		json.NewEncoder(w).Encode(account)
+	case r.Method == http.MethodGet:
+		account, ok := f.accounts[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(account)
+	case r.Method == http.MethodPut:
+		if _, ok := f.accounts[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var account AwsAccount
+		json.NewDecoder(r.Body).Decode(&account)
+		account.ID = id
+		f.accounts[id] = account
+		json.NewEncoder(w).Encode(account)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.accounts[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.accounts, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAwsAccountLifecycle(t *testing.T) {
+	tenant := &fakeTenant{accounts: map[string]AwsAccount{}}
+	client := newTestClient(t, tenant.ServeHTTP)
+	ctx := context.Background()
+
+	account := AwsAccount{
+		AccountID:    "123456789012",
+		Name:         "workload",
+		AccessConfig: AwsAccessConfig{RoleArn: "arn:aws:iam::123456789012:role/Uptycs", ExternalID: "ext"},
+		LogSources:   []AwsLogSource{{Type: LogSourceCloudTrail, BucketName: "logs", BucketRegion: "us-east-1"}},
+	}
+	created, err := client.CreateAwsAccount(ctx, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.ConnectorType != ConnectorTypeAws {
+		t.Errorf("unexpected registration %+v", created)
+	}
+	if _, err := client.CreateAwsAccount(ctx, account); !IsKind(err, ErrConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+
+	found, err := client.FindAwsAccount(ctx, "123456789012")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.ID != created.ID {
+		t.Errorf("unexpected registration %+v", found)
+	}
+	if missing, err := client.FindAwsAccount(ctx, "000000000000"); err != nil || missing != nil {
+		t.Errorf("expected no registration, got %+v, %v", missing, err)
+	}
+
+	created.AccessConfig.ExternalID = "rotated"
+	if _, err := client.UpdateAwsAccount(ctx, *created); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.GetAwsAccount(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessConfig.ExternalID != "rotated" {
+		t.Errorf("update not applied: %+v", got)
+	}
+
+	if err := client.DeleteAwsAccount(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteAwsAccount(ctx, created.ID); err != nil {
+		t.Errorf("expected a deleted registration to be skipped, got %v", err)
+	}
+	if _, err := client.GetAwsAccount(ctx, created.ID); !IsKind(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestGetAwsTrust(t *testing.T) {
+	tenant := &fakeTenant{accounts: map[string]AwsAccount{}}
+	client := newTestClient(t, tenant.ServeHTTP)
+
+	trust, err := client.GetAwsTrust(context.Background(), "123456789012")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trust.AccountID != "012345678912" || trust.ExternalID != "ext-123456789012" {
+		t.Errorf("unexpected trust %+v", trust)
+	}
+}
@@ -0,0 +1,233 @@
+// Package uptycs is a client for the REST API of an Uptycs tenant.
+package uptycs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 4
+	defaultRetryWait  = time.Second
+	maxRetryWait      = 30 * time.Second
+
+	// pageSize is the number of items requested per page of a list.
+	pageSize = 100
+)
+
+// Config holds the credentials of an API key of the tenant, as found in the
+// key file downloaded from the Uptycs console.
+type Config struct {
+	// Host is the hostname of the tenant, such as mytenant.uptycs.io. A URL
+	// with a scheme is used as is.
+	Host       string
+	APIKey     string
+	APISecret  string
+	CustomerID string
+
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// MaxRetries is the number of times a throttled or failed request is
+	// sent again. Zero means the default, a negative value disables retries.
+	MaxRetries int
+}
+
+// Client calls the API of the tenant of its Config. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	apiSecret  string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+	now        func() time.Time
+}
+
+// NewClient returns a client for the tenant of cfg.
+func NewClient(cfg Config) (*Client, error) {
+	var missing []string
+	if cfg.Host == "" {
+		missing = append(missing, "host")
+	}
+	if cfg.APIKey == "" {
+		missing = append(missing, "API key")
+	}
+	if cfg.APISecret == "" {
+		missing = append(missing, "API secret")
+	}
+	if cfg.CustomerID == "" {
+		missing = append(missing, "customer ID")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing Uptycs %s", strings.Join(missing, ", "))
+	}
+
+	host := strings.TrimSuffix(cfg.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	if _, err := url.Parse(host); err != nil {
+		return nil, fmt.Errorf("invalid Uptycs host %q: %w", cfg.Host, err)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &Client{
+		baseURL:    host + "/public/api/customers/" + url.PathEscape(cfg.CustomerID),
+		apiKey:     cfg.APIKey,
+		apiSecret:  cfg.APISecret,
+		httpClient: httpClient,
+		maxRetries: maxRetries,
+		retryWait:  defaultRetryWait,
+		now:        time.Now,
+	}, nil
+}
+
+// do sends a request to path, relative to the customer, with in as the JSON
+// body when not nil, and decodes the JSON answer into out when not nil.
+// Throttled requests are retried, as are server errors and transport
+// failures of requests that are safe to repeat.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in interface{}, out interface{}) error {
+	op := method + " " + path
+
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return &Error{Op: op, Err: err}
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	idempotent := method != http.MethodPost
+
+	for attempt := 0; ; attempt++ {
+		var wait time.Duration
+		status, data, header, err := c.send(ctx, method, target, body)
+		switch {
+		case err != nil:
+			uptErr := transportError(op, err)
+			if ctx.Err() != nil || !idempotent || attempt >= c.maxRetries {
+				return uptErr
+			}
+		case status >= 200 && status < 300:
+			if out == nil || len(bytes.TrimSpace(data)) == 0 {
+				return nil
+			}
+			if err := json.Unmarshal(data, out); err != nil {
+				return &Error{Op: op, StatusCode: status, Err: fmt.Errorf("invalid response: %w", err)}
+			}
+			return nil
+		default:
+			uptErr := responseError(op, status, data)
+			retryable := uptErr.Kind == ErrThrottled || (uptErr.Kind == ErrServer && idempotent)
+			if !retryable || attempt >= c.maxRetries {
+				return uptErr
+			}
+			wait = retryAfter(header)
+		}
+
+		if wait == 0 {
+			wait = c.retryWait << attempt
+		}
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return transportError(op, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt of a request, signing a new token for it.
+func (c *Client) send(ctx context.Context, method string, target string, body []byte) (int, []byte, http.Header, error) {
+	token, err := signToken(c.apiKey, c.apiSecret, c.now())
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return resp.StatusCode, data, resp.Header, nil
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a
+// throttled answer, or zero.
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// list requests every page of the collection at path and calls fn with the
+// items of each page, until a page is shorter than requested.
+func (c *Client) list(ctx context.Context, path string, query url.Values, fn func(items json.RawMessage) (int, error)) error {
+	for offset := 0; ; {
+		pageQuery := url.Values{}
+		for key, values := range query {
+			pageQuery[key] = values
+		}
+		pageQuery.Set("offset", strconv.Itoa(offset))
+		pageQuery.Set("limit", strconv.Itoa(pageSize))
+
+		var page struct {
+			Items json.RawMessage `json:"items"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, pageQuery, nil, &page); err != nil {
+			return err
+		}
+		count, err := fn(page.Items)
+		if err != nil {
+			return &Error{Op: http.MethodGet + " " + path, Err: fmt.Errorf("invalid response: %w", err)}
+		}
+		if count < pageSize {
+			return nil
+		}
+		offset += count
+	}
+}
@@ -0,0 +1,196 @@
+package uptycs
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := NewClient(Config{
+		Host:       server.URL,
+		APIKey:     "key",
+		APISecret:  "secret",
+		CustomerID: "customer",
+		HTTPClient: server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.retryWait = time.Millisecond
+	return client
+}
+
+func TestSignToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := signToken("key", "secret", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("unexpected token %q", token)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if parts[2] != base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Error("invalid signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "key" || claims.Iat != now.Unix() || claims.Exp != now.Add(tokenLifetime).Unix() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestNewClientMissingConfig(t *testing.T) {
+	_, err := NewClient(Config{Host: "mytenant.uptycs.io", APIKey: "key"})
+	if err == nil || err.Error() != "missing Uptycs API secret, customer ID" {
+		t.Errorf("unexpected error %v", err)
+	}
+	client, err := NewClient(Config{Host: "mytenant.uptycs.io/", APIKey: "key", APISecret: "secret", CustomerID: "customer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.baseURL != "https://mytenant.uptycs.io/public/api/customers/customer" {
+		t.Errorf("unexpected base URL %s", client.baseURL)
+	}
+}
+
+func TestDoSignsRequests(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/public/api/customers/customer/things" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("missing token")
+		}
+		var in map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprintf(w, `{"name":%q}`, in["name"])
+	})
+	var out map[string]string
+	if err := client.do(context.Background(), http.MethodPost, "/things", nil, map[string]string{"name": "a"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["name"] != "a" {
+		t.Errorf("unexpected answer %v", out)
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	})
+	if err := client.do(context.Background(), http.MethodGet, "/things", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	// A failed creation may have been applied, it is not sent again.
+	calls = 0
+	err := client.do(context.Background(), http.MethodPost, "/things", nil, map[string]string{}, nil)
+	if calls != 2 || !IsKind(err, ErrServer) {
+		t.Errorf("expected a server error after 2 calls, got %v after %d", err, calls)
+	}
+}
+
+func TestDoErrorMapping(t *testing.T) {
+	cases := []struct {
+		status int
+		kind   ErrorKind
+	}{
+		{http.StatusBadRequest, ErrInvalidRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+	}
+	for _, c := range cases {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			fmt.Fprint(w, `{"error":{"status":`+strconv.Itoa(c.status)+`,"code":"CODE","message":{"brief":"brief","detail":"detail"}}}`)
+		})
+		err := client.do(context.Background(), http.MethodGet, "/things", nil, nil, nil)
+		uptErr, ok := AsError(err)
+		if !ok {
+			t.Fatalf("%d: expected *Error, got %T", c.status, err)
+		}
+		if uptErr.Kind != c.kind || uptErr.Code != "CODE" || uptErr.Message != "brief: detail" {
+			t.Errorf("%d: unexpected error %+v", c.status, uptErr)
+		}
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.do(ctx, http.MethodGet, "/things", nil, nil, nil); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestList(t *testing.T) {
+	const total = 2*pageSize + 10
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if r.URL.Query().Get("filter") != "x" {
+			t.Errorf("query not kept: %s", r.URL.RawQuery)
+		}
+		items := []int{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			items = append(items, i)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "offset": offset, "limit": limit})
+	})
+	var all []int
+	err := client.list(context.Background(), "/things", map[string][]string{"filter": {"x"}}, func(items json.RawMessage) (int, error) {
+		var page []int
+		if err := json.Unmarshal(items, &page); err != nil {
+			return 0, err
+		}
+		all = append(all, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != total || all[total-1] != total-1 {
+		t.Errorf("unexpected items, got %d", len(all))
+	}
+}
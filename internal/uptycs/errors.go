@@ -0,0 +1,180 @@
+package uptycs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorKind classifies an Uptycs API failure so callers can react to it and
+// report it to the practitioner with a useful remediation.
+type ErrorKind int
+
+const (
+	ErrUnknown ErrorKind = iota
+	ErrUnauthorized
+	ErrForbidden
+	ErrNotFound
+	ErrConflict
+	ErrInvalidRequest
+	ErrThrottled
+	ErrServer
+	ErrTimeout
+)
+
+// Error is returned by this package for every failed API call. It keeps the
+// operation that was being executed along with the classified kind and what
+// the tenant answered.
+type Error struct {
+	Kind ErrorKind
+	Op   string
+
+	// StatusCode, Code and Message are set when the tenant answered.
+	StatusCode int
+	Code       string
+	Message    string
+
+	// Err is set when no answer was received.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Op, e.Err)
+	}
+	msg := fmt.Sprintf("%s: %d %s", e.Op, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Summary is a short, human readable description of the failure.
+func (e *Error) Summary() string {
+	switch e.Kind {
+	case ErrUnauthorized:
+		return "Uptycs Authentication Failed"
+	case ErrForbidden:
+		return "Uptycs Permission Denied"
+	case ErrNotFound:
+		return "Uptycs Object Not Found"
+	case ErrConflict:
+		return "Uptycs Object Already Exists"
+	case ErrInvalidRequest:
+		return "Uptycs Request Rejected"
+	case ErrThrottled:
+		return "Uptycs Request Throttled"
+	case ErrServer:
+		return "Uptycs Server Error"
+	case ErrTimeout:
+		return "Uptycs Request Timed Out"
+	}
+	return "Uptycs API Error"
+}
+
+// Remediation is a hint describing how the practitioner can fix the failure.
+func (e *Error) Remediation() string {
+	switch e.Kind {
+	case ErrUnauthorized:
+		return "Check that uptycs_api_key, uptycs_api_secret and uptycs_customer_id of the provider match an API key " +
+			"of the tenant, and that the clock of this machine is accurate."
+	case ErrForbidden:
+		return "The API key is not allowed to perform this operation. Use the key of a user with the required role."
+	case ErrNotFound:
+		return "The object does not exist in the tenant. It may have been deleted outside of Terraform."
+	case ErrConflict:
+		return "An object with the same identity already exists in the tenant. Import it into the Terraform state instead."
+	case ErrInvalidRequest:
+		return "The tenant rejected the request. Check the message returned by Uptycs for the invalid value."
+	case ErrThrottled:
+		return "Uptycs throttled the request. Retry the operation later or reduce the apply parallelism."
+	case ErrServer:
+		return "The tenant failed to process the request. Retry the operation later."
+	case ErrTimeout:
+		return "The operation did not finish within its timeout. Increase the matching value in the timeouts block " +
+			"or check the connectivity to the tenant."
+	}
+	return "Inspect the error returned by Uptycs for more details."
+}
+
+// AsError returns the *Error from err's chain, if any.
+func AsError(err error) (*Error, bool) {
+	var uptErr *Error
+	if errors.As(err, &uptErr) {
+		return uptErr, true
+	}
+	return nil, false
+}
+
+// IsKind reports whether err was classified as kind.
+func IsKind(err error, kind ErrorKind) bool {
+	if uptErr, ok := AsError(err); ok {
+		return uptErr.Kind == kind
+	}
+	return false
+}
+
+// transportError wraps a failure to get an answer from the tenant.
+func transportError(op string, err error) *Error {
+	kind := ErrUnknown
+	if errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrTimeout
+	}
+	return &Error{Kind: kind, Op: op, Err: err}
+}
+
+// responseError builds the error for a non-2xx answer from its status and
+// the error document in body, when there is one.
+func responseError(op string, statusCode int, body []byte) *Error {
+	uptErr := &Error{Kind: classify(statusCode), Op: op, StatusCode: statusCode}
+
+	var doc struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message struct {
+				Brief  string `json:"brief"`
+				Detail string `json:"detail"`
+			} `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &doc) == nil {
+		uptErr.Code = doc.Error.Code
+		uptErr.Message = doc.Error.Message.Brief
+		if doc.Error.Message.Detail != "" && doc.Error.Message.Detail != uptErr.Message {
+			if uptErr.Message != "" {
+				uptErr.Message += ": "
+			}
+			uptErr.Message += doc.Error.Message.Detail
+		}
+	}
+	return uptErr
+}
+
+func classify(statusCode int) ErrorKind {
+	switch {
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusConflict:
+		return ErrConflict
+	case statusCode == http.StatusTooManyRequests:
+		return ErrThrottled
+	case statusCode >= 500:
+		return ErrServer
+	case statusCode >= 400:
+		return ErrInvalidRequest
+	}
+	return ErrUnknown
+}
@@ -0,0 +1,34 @@
+package uptycs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// tokenLifetime is how long a signed token is accepted by the tenant. A new
+// token is signed for every request, so it only has to cover one round trip.
+const tokenLifetime = 5 * time.Minute
+
+// signToken returns an HS256 JSON Web Token issued by the API key and signed
+// with the API secret, as expected by the Uptycs API.
+func signToken(apiKey string, apiSecret string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": apiKey,
+		"iat": now.Unix(),
+		"exp": now.Add(tokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}